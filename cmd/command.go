@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// Command is a complete command-line: the command to run, and its options.
+// Secrets are the values (from `secret` fields, or the WithSecrets() option)
+// that are masked whenever the command-line is logged.
+type Command struct {
+	Name    string
+	Options []string
+	Secrets []string
+}
+
+// Args returns the command-line as a single slice, with the command first.
+func (c Command) Args() []string {
+	return append([]string{c.Name}, c.Options...)
+}
+
+// String returns the command-line quoted for a POSIX shell (see Quote()).
+func (c Command) String() string {
+	return Quote(c.Args())
+}
+
+// Redacted returns the command-line quoted for a POSIX shell, with any secret
+// values masked (see Redact()).  This is the form that is safe to log.
+func (c Command) Redacted() string {
+	return Quote(Redact(c.Args(), c.Secrets...))
+}
+
+// WriteScript writes a standalone shell script that runs the command, so that
+// a failing step can be re-run locally.  Secret values are masked in the
+// script, and need to be filled in by hand.
+func (c Command) WriteScript(path string) (err error) {
+	script := fmt.Sprintf("#!/bin/sh\n"+
+		"# Generated by drone-plugin-helper.  Any secret values have been masked\n"+
+		"# (as %s), and need to be filled in before running.\n"+
+		"set -e\n"+
+		"%s\n", Mask, c.Redacted())
+
+	err = os.WriteFile(path, []byte(script), 0755)
+	return
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandString(t *testing.T) {
+	c := Command{Name: "helm", Options: []string{"delete", "--purge", "my release"}}
+
+	expected := "helm delete --purge 'my release'"
+	if actual := c.String(); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestCommandRedacted(t *testing.T) {
+	c := Command{Name: "helm", Options: []string{"--password", "correct horse"}, Secrets: []string{"correct horse"}}
+
+	expected := "helm --password '******'"
+	if actual := c.Redacted(); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rerun.sh")
+
+	err := Run("true", []string{"two words"}, Script(path))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading script: %v", err)
+	}
+	script := string(b)
+	if !strings.HasPrefix(script, "#!/bin/sh\n") {
+		t.Errorf("script is missing shebang: %q", script)
+	}
+	if !strings.HasSuffix(script, "\ntrue 'two words'\n") {
+		t.Errorf("script does not end with the command: %q", script)
+	}
+
+	// ... and the script itself should run
+	err = Run("sh", []string{path})
+	if err != nil {
+		t.Errorf("unexpected error running script: %v", err)
+	}
+}
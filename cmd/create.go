@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/JaredReisinger/drone-plugin-helper/names"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
 )
 
 const (
@@ -21,38 +22,161 @@ const (
 // 	termRe  = regexp.MustCompile(termReString)
 // )
 
+// ExtraArgsField is the conventional name for a field that holds extra, raw
+// arguments for the underlying tool: no params struct will ever cover every
+// option a tool has.  A string value is split into words the way a shell would
+// (see SplitWords()), and a []string value is used as-is.  The extra arguments
+// are added after the generated flags but before any positional arguments;
+// tag options like `strict` don't change that, but the `extra` option puts
+// them in the field's own place instead, and a flag name in the tag makes the
+// field an ordinary flag.  (A field with any name can be tagged with the
+// `extra` option to use its value as extra arguments at that position in the
+// command-line.)
+const ExtraArgsField = "ExtraArgs"
+
+// Metadata reports whether a field (identified by its Go path, like
+// "GlobalParams.Debug") was explicitly set.  An *env.Metadata from
+// env.ParseWithMetadata() satisfies this interface.
+type Metadata interface {
+	IsSet(path string) bool
+}
+
 // Create generates the command line
 // TODO: pass the order? Is that defined by the struct?
 func Create(cfg interface{}) (params []string, err error) {
-	s, _ := indirect(reflect.ValueOf(cfg))
-	params, err = createStructFlags(s)
+	return CreateWithMetadata(cfg, nil)
+}
+
+// CreateWithMetadata is like Create(), but also emits options for any fields
+// that md reports as explicitly set, even if they hold the "zero value" for
+// their type.  (Without metadata, only pointer fields can do this.)
+func CreateWithMetadata(cfg interface{}, md Metadata) (params []string, err error) {
+	return (&Encoder{}).CreateWithMetadata(cfg, md)
+}
+
+// CreateCommand creates the complete command-line for the command, like
+// Create(), along with the values of any `secret` fields, so that
+// Command.Redacted() can mask them.
+func CreateCommand(command string, cfg interface{}, md Metadata) (c Command, err error) {
+	return (&Encoder{}).CreateCommand(command, cfg, md)
+}
+
+// Create is like the package-level Create(), using the Encoder's naming.
+func (e *Encoder) Create(cfg interface{}) (params []string, err error) {
+	return e.CreateWithMetadata(cfg, nil)
+}
+
+// CreateWithMetadata is like the package-level CreateWithMetadata(), using
+// the Encoder's naming.
+func (e *Encoder) CreateWithMetadata(cfg interface{}, md Metadata) (params []string, err error) {
+	c, err := e.CreateCommand("", cfg, md)
+	params = c.Options
 	return
 }
 
-func createStructFlags(s reflect.Value) (params []string, err error) {
-	params = make([]string, 0)
-	for i := 0; i < s.NumField(); i++ {
-		field, _ := indirect(s.Field(i))
-		if field.Kind() == reflect.Struct {
-			var innerParams []string
-			innerParams, err = createStructFlags(field)
-			if err != nil {
-				return
-			}
-			// if len(innerParams) > 0 {
-			params = append(params, innerParams...)
-			// }
+// CreateCommand is like the package-level CreateCommand(), using the
+// Encoder's naming.
+func (e *Encoder) CreateCommand(command string, cfg interface{}, md Metadata) (c Command, err error) {
+	s, _ := indirect(reflect.ValueOf(cfg))
+	fs, err := e.structFields(s.Type())
+	if err != nil {
+		return
+	}
+
+	l := newLine()
+	for _, f := range fs {
+		val, ok := f.Value(s)
+		if !ok {
 			continue
 		}
-
-		err = addFieldFlag(&params, s.Type().Field(i), field)
+		set := md != nil && md.IsSet(f.Path)
+		err = addFieldFlag(l, f, val, set)
 		if err != nil {
 			return
 		}
 	}
+
+	err = l.checkExtra()
+	if err != nil {
+		return
+	}
+
+	c = Command{Name: command, Options: l.finish(), Secrets: l.secrets}
 	return
 }
 
+// line accumulates the command-line as it is created.
+type line struct {
+	args       []string
+	positional int               // index of the first positional argument, or -1
+	extra      []string          // extra arguments to insert before the positionals
+	strict     []string          // extra arguments that may not repeat a generated flag
+	secrets    []string          // values of `secret` fields, to be masked when logged
+	flags      map[string]string // generated flags, and the path of the field that generated them
+}
+
+func newLine() *line {
+	return &line{
+		args:       make([]string, 0),
+		positional: -1,
+		flags:      make(map[string]string),
+	}
+}
+
+func (l *line) addFlag(flag string, fieldPath string) {
+	l.args = append(l.args, flag)
+	l.flags[flag] = fieldPath
+}
+
+// addOption adds a value to the line (preceded by its flag if it's not
+// positional), remembering it for masking if the field is a secret.
+func (l *line) addOption(info tagInfo, fieldPath string, value string) {
+	if info.positional {
+		if l.positional < 0 {
+			l.positional = len(l.args)
+		}
+	} else {
+		l.addFlag(info.flag, fieldPath)
+	}
+
+	if info.secret {
+		l.secrets = append(l.secrets, value)
+	}
+	l.args = append(l.args, value)
+}
+
+// checkExtra ensures that none of the `strict` extra arguments repeat a flag
+// that was generated from the struct.
+func (l *line) checkExtra() error {
+	for _, arg := range l.strict {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		flag, _, _ := strings.Cut(arg, "=")
+		if fieldPath, ok := l.flags[flag]; ok {
+			return fmt.Errorf("extra argument %q conflicts with the %q flag from field %s", arg, flag, fieldPath)
+		}
+	}
+	return nil
+}
+
+// finish returns the completed command-line, with any extra arguments inserted
+// before the first positional argument.
+func (l *line) finish() []string {
+	if len(l.extra) == 0 {
+		return l.args
+	}
+	if l.positional < 0 {
+		return append(l.args, l.extra...)
+	}
+
+	args := make([]string, 0, len(l.args)+len(l.extra))
+	args = append(args, l.args[:l.positional]...)
+	args = append(args, l.extra...)
+	args = append(args, l.args[l.positional:]...)
+	return args
+}
+
 // indirect returns the underlying field (so long as the pointer isn't null)
 func indirect(field reflect.Value) (fieldOut reflect.Value, hadPtr bool) {
 	fieldOut = field
@@ -63,61 +187,127 @@ func indirect(field reflect.Value) (fieldOut reflect.Value, hadPtr bool) {
 	return
 }
 
-func addFieldFlag(line *[]string, sf reflect.StructField, val reflect.Value) (err error) {
-	info, err := infoFromField(sf)
-	if err != nil {
-		return
-	}
+// addFieldFlag adds the flag and/or value for the field to the line.  A zero
+// value is only added if the field is a pointer or an optional.Value, or was
+// explicitly set.
+func addFieldFlag(l *line, f *field, val reflect.Value, set bool) (err error) {
+	info := f.info
 	// log.Printf("using info %+v", info)
 	if info.omit {
 		return
 	}
 
 	field, hadPtr := indirect(val)
+	// a nil pointer has nothing to emit
+	if !field.IsValid() {
+		return
+	}
+
+	// an optional.Value is only emitted when set, but then always emitted (just
+	// like a non-nil pointer)
+	if o, ok := optional.From(field); ok {
+		if !o.IsSet() {
+			return
+		}
+		field, _ = indirect(o.Reflect())
+		if !field.IsValid() {
+			return
+		}
+		hadPtr = true
+	}
+
+	if info.extra {
+		var words []string
+		words, err = extraWords(field, f.Name)
+		if err != nil {
+			return
+		}
+		if info.strict {
+			l.strict = append(l.strict, words...)
+		}
+		if info.beforePositionals {
+			l.extra = append(l.extra, words...)
+		} else {
+			l.args = append(l.args, words...)
+		}
+		return
+	}
+
 	kind := field.Kind()
 	// log.Printf("adding flag for %v...", kind)
 	switch kind {
 
 	case reflect.Bool:
 		// no check for hadPtr?
-		if val.Bool() {
-			*line = append(*line, info.flag)
+		if field.Bool() {
+			l.addFlag(info.flag, f.Path)
 		} else if info.boolNo {
-			negatedFlag, ok := negatedBool(info.flag)
-			if !ok {
+			if info.negated == "" {
 				err = fmt.Errorf("unable to negate boolean flag %q", info.flag)
 				return
 			}
-			*line = append(*line, negatedFlag)
+			l.addFlag(info.negated, f.Path)
 		}
 
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if hadPtr || val.Int() != 0 {
-			if !info.positional {
-				*line = append(*line, info.flag)
+	case reflect.Slice:
+		// Each element is a separate option: "--set a --set b", or simply
+		// "a b" if positional.
+		for i := 0; i < field.Len(); i++ {
+			value, _, ok := formatValue(field.Index(i))
+			if !ok {
+				err = fmt.Errorf("unsupported parameter type for %q: %q", f.Name, field.Type())
+				return
 			}
-			*line = append(*line, strconv.FormatInt(val.Int(), 10))
+			l.addOption(info, f.Path, value)
 		}
 
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if hadPtr || val.Uint() != 0 {
-			if !info.positional {
-				*line = append(*line, info.flag)
-			}
-			*line = append(*line, strconv.FormatUint(val.Uint(), 10))
+	default:
+		value, zero, ok := formatValue(field)
+		if !ok {
+			err = fmt.Errorf("unsupported parameter type for %q: %q", f.Name, kind)
+			return
+		}
+		if hadPtr || set || !zero {
+			l.addOption(info, f.Path, value)
 		}
+	}
+
+	return
+}
+
+// formatValue returns the command-line representation of a scalar value, and
+// whether it is the "zero value" for its type.
+func formatValue(val reflect.Value) (value string, zero bool, ok bool) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), val.Int() == 0, true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10), val.Uint() == 0, true
 
 	case reflect.String:
-		if hadPtr || val.String() != "" {
-			if !info.positional {
-				*line = append(*line, info.flag)
-			}
-			*line = append(*line, val.String())
+		return val.String(), val.String() == "", true
+	}
+
+	return
+}
+
+// extraWords returns the extra arguments from an `extra` field.
+func extraWords(field reflect.Value, name string) (words []string, err error) {
+	switch {
+	case field.Kind() == reflect.String:
+		words, err = SplitWords(field.String())
+		if err != nil {
+			err = fmt.Errorf("unable to split extra arguments in %q: %w", name, err)
+		}
+
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < field.Len(); i++ {
+			words = append(words, field.Index(i).String())
 		}
 
 	default:
-		err = fmt.Errorf("unsupported parameter type for %q: %q", sf.Name, kind)
-		return
+		err = fmt.Errorf("extra arguments in %q must be a string or []string, not %q", name, field.Type())
 	}
 
 	return
@@ -125,32 +315,48 @@ func addFieldFlag(line *[]string, sf reflect.StructField, val reflect.Value) (er
 
 type tagInfo struct {
 	flag       string
+	negated    string // the negated flag (for `no`), if it can be derived
 	omit       bool
 	positional bool
 	boolNo     bool
+	secret     bool
+	extra      bool
+	strict     bool
+
+	// beforePositionals is set for the conventional ExtraArgsField, whose
+	// arguments go before the positional arguments rather than in place.
+	beforePositionals bool
 }
 
-func infoFromField(sf reflect.StructField) (info tagInfo, err error) {
+func infoFromField(sf reflect.StructField, set *names.Set, naming Naming) (info tagInfo, err error) {
 	tag := sf.Tag.Get(tagName)
 
 	if tag != "" {
 		// log.Printf("got flag tag %q for %q...", tag, sf.Name)
 		info, err = parseTagInfo(tag)
 		// log.Printf("got info/err: %+v %+v", info, err)
-		// } else {
-		// 	log.Printf("no flag tag for %q... ???", sf.Name)
-	}
-
-	if info.flag == "" {
-		param, ok := fieldToParamName(sf.Name)
-		if !ok {
-			err = fmt.Errorf("unable to create param from %q", sf.Name)
+		if err != nil {
 			return
 		}
-		info.flag = fmt.Sprintf("--%s", param)
+	}
+	if sf.Name == ExtraArgsField && !info.extra && info.flag == "" {
+		info.extra = true
+		info.beforePositionals = true
 	}
 
-	// info, err = parseTagInfo(tag)
+	if info.flag != "" {
+		info.negated, _ = negatedBool(info.flag)
+		return
+	}
+
+	words, err := set.Split(sf.Name)
+	if err != nil {
+		err = fmt.Errorf("unable to create param from %q", sf.Name)
+		return
+	}
+	naming = naming.withDefaults()
+	info.flag = naming.flag(words)
+	info.negated = naming.flag(append([]string{"no"}, words...))
 	return
 }
 
@@ -175,6 +381,12 @@ func parseTagInfo(tag string) (info tagInfo, err error) {
 			info.boolNo = true
 		case "positional":
 			info.positional = true
+		case "secret":
+			info.secret = true
+		case "extra":
+			info.extra = true
+		case "strict":
+			info.strict = true
 		default:
 			err = fmt.Errorf("unknown cmd tag option: %q", part)
 			return
@@ -184,21 +396,10 @@ func parseTagInfo(tag string) (info tagInfo, err error) {
 	return
 }
 
-func fieldToParamName(name string) (string, bool) {
-	terms, err := names.Split(name)
-	if err != nil {
-		return "", false
-	}
-
-	var b strings.Builder
-	for i, term := range terms {
-		if i > 0 {
-			b.WriteString("-")
-		}
-		b.WriteString(strings.ToLower(term))
-	}
-	// TODO: separate and hyphenate on capital letter boundaries
-	return b.String(), true
+// fieldToParamName returns the kebab-case parameter name for a field name.
+func fieldToParamName(name string, set *names.Set) (string, bool) {
+	param, err := set.Convert(name, names.Kebab)
+	return param, err == nil
 }
 
 func negatedBool(flag string) (string, bool) {
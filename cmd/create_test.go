@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
 )
 
 func TestFieldToParamName(t *testing.T) {
@@ -14,12 +17,15 @@ func TestFieldToParamName(t *testing.T) {
 		{"Simple", "simple"},
 		{"TwoWords", "two-words"},
 		{"ThreeWith1Number", "three-with1-number"},
-		{"invalidFormat", ""},
+		{"invalidFormat", "invalid-format"},
+		{"S3Bucket", "s3-bucket"},
+		{"_", ""},
 		{"TLSCertID", "tls-cert-id"},
+		{"ImageIDs", "image-ids"},
 	}
 
 	for _, ex := range examples {
-		actual, ok := fieldToParamName(ex.in)
+		actual, ok := fieldToParamName(ex.in, names.Default())
 		if !ok && ex.expected != "" {
 			t.Errorf("unexpected failure with %q", ex.in)
 		} else if actual != ex.expected {
@@ -39,6 +45,7 @@ func TestParseTagInfo(t *testing.T) {
 		{",no", &tagInfo{boolNo: true}},
 		{",positional", &tagInfo{positional: true}},
 		{",no,positional", &tagInfo{boolNo: true, positional: true}},
+		{",secret", &tagInfo{secret: true}},
 		{",bogus", nil},
 	}
 
@@ -63,7 +70,8 @@ func TestInfoFromField(t *testing.T) {
 		{"Simple", "", &tagInfo{flag: "--simple"}},
 		{"Dummy", "--override", &tagInfo{flag: "--override"}},
 		// {"", "", &tagInfo{}},
-		{"bogus", "", nil}, // should fail!
+		{"bogus", "", &tagInfo{flag: "--bogus"}},
+		{"_", "", nil}, // should fail!
 	}
 
 	for _, ex := range examples {
@@ -71,7 +79,7 @@ func TestInfoFromField(t *testing.T) {
 			Name: ex.name,
 			Tag:  reflect.StructTag(fmt.Sprintf("%s:\"%s\"", tagName, ex.tag)),
 		}
-		actual, err := infoFromField(sf)
+		actual, err := infoFromField(sf, names.Default(), Naming{})
 		if err != nil {
 			if ex.expected != nil {
 				t.Errorf("unexpected failure with %q/%q: %v", ex.name, ex.tag, err)
@@ -95,6 +103,9 @@ func tagInfoChecker(t *testing.T, expected *tagInfo, actual *tagInfo) {
 	if actual.boolNo != expected.boolNo {
 		t.Errorf("expected boolNo to be %v, got %v", expected.boolNo, actual.boolNo)
 	}
+	if actual.secret != expected.secret {
+		t.Errorf("expected secret to be %v, got %v", expected.secret, actual.secret)
+	}
 }
 
 func TestNegatedBool(t *testing.T) {
@@ -120,3 +131,191 @@ func TestNegatedBool(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateSlices(t *testing.T) {
+	params := struct {
+		Set     []string
+		Max     []int
+		Plugins []string `cmd:",positional"`
+	}{
+		Set:     []string{"a=1", "b=2"},
+		Max:     []int{3},
+		Plugins: []string{"one", "two"},
+	}
+
+	actual, err := Create(&params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equalStrings(t, []string{"--set", "a=1", "--set", "b=2", "--max", "3", "one", "two"}, actual)
+}
+
+func TestCreateExtraArgs(t *testing.T) {
+	examples := []struct {
+		name     string
+		params   interface{}
+		expected []string
+	}{
+		{
+			"before positionals",
+			&struct {
+				Debug     bool
+				Release   string `cmd:",positional"`
+				Wait      bool
+				ExtraArgs string
+			}{true, "my-release", true, `--set 'a=b c' --atomic`},
+			[]string{"--debug", "--set", "a=b c", "--atomic", "my-release", "--wait"},
+		},
+		{
+			"no positionals",
+			&struct {
+				Debug     bool
+				ExtraArgs []string
+			}{true, []string{"--set", "a=b c"}},
+			[]string{"--debug", "--set", "a=b c"},
+		},
+		{
+			"tagged position",
+			&struct {
+				Debug   bool
+				Raw     string `cmd:",extra"`
+				Release string `cmd:",positional"`
+				Wait    bool
+			}{true, "--atomic", "my-release", true},
+			[]string{"--debug", "--atomic", "my-release", "--wait"},
+		},
+		{
+			"tagged flag",
+			&struct {
+				ExtraArgs string `cmd:"--extra-args"`
+			}{"value"},
+			[]string{"--extra-args", "value"},
+		},
+		{
+			"tagged strict",
+			&struct {
+				Release   string `cmd:",positional"`
+				ExtraArgs string `cmd:",strict"`
+			}{"my-release", "--atomic"},
+			[]string{"--atomic", "my-release"},
+		},
+		{
+			"tagged extra",
+			&struct {
+				Release   string `cmd:",positional"`
+				ExtraArgs string `cmd:",extra"`
+			}{"my-release", "--atomic"},
+			[]string{"my-release", "--atomic"},
+		},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			actual, err := Create(local.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			equalStrings(t, local.expected, actual)
+		})
+	}
+}
+
+func TestCreateExtraArgsErrors(t *testing.T) {
+	examples := []struct {
+		name   string
+		params interface{}
+	}{
+		{
+			"unterminated quote",
+			&struct{ ExtraArgs string }{`--set 'a=b`},
+		},
+		{
+			"unsupported type",
+			&struct{ ExtraArgs int }{1},
+		},
+		{
+			"strict conflict",
+			&struct {
+				Debug bool
+				Raw   string `cmd:",extra,strict"`
+			}{true, "--debug=false"},
+		},
+		{
+			"strict conflict by name",
+			&struct {
+				Debug     bool
+				ExtraArgs string `cmd:",strict"`
+			}{true, "--debug=false"},
+		},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			_, err := Create(local.params)
+			if err == nil {
+				t.Error("missing expected error")
+			}
+		})
+	}
+}
+
+type setPaths map[string]bool
+
+func (s setPaths) IsSet(path string) bool { return s[path] }
+
+func TestCreateWithMetadata(t *testing.T) {
+	params := struct {
+		Retries int
+		Timeout int
+		Name    string
+	}{}
+
+	actual, err := CreateWithMetadata(&params, setPaths{"Retries": true, "Name": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equalStrings(t, []string{"--retries", "0", "--name", ""}, actual)
+
+	actual, err = Create(&params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equalStrings(t, []string{}, actual)
+}
+
+func TestCreateOptional(t *testing.T) {
+	params := struct {
+		Retries optional.Value[int]
+		Timeout optional.Value[int]
+		Verbose optional.Value[bool]
+		Set     optional.Value[[]string]
+		Release optional.Value[string] `cmd:",positional"`
+	}{
+		Retries: optional.Of(0),
+		Verbose: optional.Of(true),
+		Set:     optional.Of([]string{"a=1"}),
+		Release: optional.Of("demo"),
+	}
+
+	actual, err := Create(&params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equalStrings(t, []string{"--retries", "0", "--verbose", "--set", "a=1", "demo"}, actual)
+}
+
+func TestCreateUnexported(t *testing.T) {
+	params := struct {
+		Name       string
+		maxRetries int
+		count      optional.Value[int]
+	}{Name: "demo", maxRetries: 3, count: optional.Of(3)}
+
+	actual, err := Create(&params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equalStrings(t, []string{"--name", "demo"}, actual)
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	simplePkg   = "github.com/JaredReisinger/drone-plugin-helper/simple"
+	optionalPkg = "github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+// plugin is everything the documentation is generated from.
+type plugin struct {
+	Tool     string     // the command-line tool the plugin runs, like "helm"
+	Routed   bool       // the commands are chosen by the `command` setting
+	Commands []*command // in registration order
+}
+
+// command is a single registered command (or the only one, for a plugin that
+// uses simple.Exec()).
+type command struct {
+	Path     string            // like "dependency build"; empty for an unrouted plugin
+	TypeName string            // the Go type of the params
+	Type     reflect.Type      // a stand-in for the params type; see loader
+	Defaults map[string]string // Go path => default value, from composite literals
+}
+
+// loader turns the static types of a package (as loaded by go/packages) into
+// reflect types, so that the usage package can describe them exactly as the
+// helpers would see them at runtime: the same field plans, setting names,
+// flags and validation rules.  Go doc comments on fields are added as `doc`
+// tags, for fields that don't have one of their own.  Since the stand-in
+// types have no methods, a FlagNamer implementation isn't seen.
+type loader struct {
+	pkg     *packages.Package
+	docs    map[*types.Var]string
+	structs map[string]reflect.Type
+}
+
+// load finds the commands registered (or executed) by the package.  If
+// typeName is given, only that params type is documented.
+func load(pattern string, typeName string) (p *plugin, err error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return
+	}
+	if len(pkgs) != 1 {
+		err = fmt.Errorf("expected a single package for %q, got %d", pattern, len(pkgs))
+		return
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		err = fmt.Errorf("unable to load %q", pattern)
+		return
+	}
+
+	l := &loader{pkg: pkgs[0], docs: make(map[*types.Var]string), structs: make(map[string]reflect.Type)}
+	l.collectDocs()
+
+	p = &plugin{Tool: l.pkg.Name}
+	if typeName != "" {
+		obj := l.pkg.Types.Scope().Lookup(typeName)
+		if obj == nil {
+			err = fmt.Errorf("type %q not found in %s", typeName, l.pkg.PkgPath)
+			return
+		}
+		err = l.add(p, "", obj.Type(), nil)
+		return
+	}
+
+	err = l.findCommands(p)
+	if err == nil && len(p.Commands) == 0 {
+		err = fmt.Errorf("no commands found in %s (use -type to document a params type)", l.pkg.PkgPath)
+	}
+	return
+}
+
+// collectDocs records the doc comments of all of the struct fields in the
+// package.
+func (l *loader) collectDocs() {
+	for _, file := range l.pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			st, ok := n.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				doc := field.Doc
+				if doc == nil {
+					doc = field.Comment
+				}
+				if doc == nil {
+					continue
+				}
+				text := strings.Join(strings.Fields(doc.Text()), " ")
+				for _, name := range field.Names {
+					if v, ok := l.pkg.TypesInfo.Defs[name].(*types.Var); ok {
+						l.docs[v] = text
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// findCommands looks for calls into the simple package: Exec() and Execute()
+// for a single command, and Register(), RegisterFunc(), Registry.Handle(),
+// Registry.HandleType() and ExecCommand() for routed commands.  The command
+// paths must be constants.
+func (l *loader) findCommands(p *plugin) (err error) {
+	for _, file := range l.pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || err != nil {
+				return err == nil
+			}
+			err = l.inspectCall(p, call)
+			return err == nil
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (l *loader) inspectCall(p *plugin, call *ast.CallExpr) error {
+	fun := call.Fun
+	switch index := fun.(type) {
+	case *ast.IndexExpr:
+		fun = index.X
+	case *ast.IndexListExpr:
+		fun = index.X
+	}
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	fn, ok := l.pkg.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != simplePkg {
+		return nil
+	}
+	isMethod := fn.Type().(*types.Signature).Recv() != nil
+
+	switch {
+	case fn.Name() == "Exec" || fn.Name() == "Execute":
+		if tool, ok := l.stringArg(call, 0); ok {
+			p.Tool = tool
+		}
+		if !isMethod && len(call.Args) > 1 {
+			return l.add(p, "", l.pkg.TypesInfo.TypeOf(call.Args[1]), call.Args[1])
+		}
+
+	case fn.Name() == "ExecCommand" || fn.Name() == "ExecuteCommand":
+		if tool, ok := l.stringArg(call, 0); ok {
+			p.Tool = tool
+		}
+		p.Routed = true
+		lit, ok := unparen(call.Args[1]).(*ast.CompositeLit)
+		if !ok {
+			return fmt.Errorf("%s: the params map must be a literal", l.pkg.Fset.Position(call.Pos()))
+		}
+		for _, elt := range lit.Elts {
+			kv := elt.(*ast.KeyValueExpr)
+			path, ok := l.constString(kv.Key)
+			if !ok {
+				return fmt.Errorf("%s: the command path must be a constant", l.pkg.Fset.Position(kv.Pos()))
+			}
+			if err := l.add(p, path, l.pkg.TypesInfo.TypeOf(kv.Value), kv.Value); err != nil {
+				return err
+			}
+		}
+
+	case fn.Name() == "Register" || fn.Name() == "RegisterFunc" || fn.Name() == "Handle" || fn.Name() == "HandleType":
+		p.Routed = true
+		pathArg := 1
+		if isMethod {
+			pathArg = 0
+		}
+		path, ok := l.stringArg(call, pathArg)
+		if !ok {
+			return fmt.Errorf("%s: the command path must be a constant", l.pkg.Fset.Position(call.Pos()))
+		}
+
+		switch fn.Name() {
+		case "Register":
+			inst := l.pkg.TypesInfo.Instances[sel.Sel]
+			return l.add(p, path, inst.TypeArgs.At(0), nil)
+		case "RegisterFunc":
+			inst := l.pkg.TypesInfo.Instances[sel.Sel]
+			return l.add(p, path, inst.TypeArgs.At(0), returnedLiteral(call.Args[2]))
+		case "Handle":
+			return l.add(p, path, l.pkg.TypesInfo.TypeOf(call.Args[1]), call.Args[1])
+		case "HandleType":
+			// like reflect.TypeOf(Params{})
+			if inner, ok := unparen(call.Args[1]).(*ast.CallExpr); ok && len(inner.Args) == 1 {
+				return l.add(p, path, l.pkg.TypesInfo.TypeOf(inner.Args[0]), inner.Args[0])
+			}
+			return fmt.Errorf("%s: expected reflect.TypeOf(...) for the params type", l.pkg.Fset.Position(call.Pos()))
+		}
+	}
+
+	return nil
+}
+
+// add adds a command for the params type, with any defaults from expr.
+func (l *loader) add(p *plugin, path string, typ types.Type, expr ast.Expr) error {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("params for command %q are not a struct: %s", path, typ)
+	}
+
+	c := &command{
+		Path:     path,
+		TypeName: types.TypeString(typ, types.RelativeTo(l.pkg.Types)),
+		Type:     l.structType(typ.String(), st),
+		Defaults: make(map[string]string),
+	}
+	if lit := compositeLiteral(expr); lit != nil {
+		l.collectDefaults(lit, "", c.Defaults)
+	}
+	p.Commands = append(p.Commands, c)
+	return nil
+}
+
+// structType returns the stand-in reflect type for the struct.  The
+// simple.Command struct is left out, since the `command` setting is
+// documented separately.
+func (l *loader) structType(key string, st *types.Struct) reflect.Type {
+	if typ, ok := l.structs[key]; ok {
+		return typ
+	}
+
+	var sfs []reflect.StructField
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Exported() || isSimpleCommand(v.Type()) {
+			continue
+		}
+		typ, ok := l.reflectType(v.Type())
+		if !ok {
+			continue
+		}
+
+		tag := st.Tag(i)
+		if doc, ok := l.docs[v]; ok && !hasTag(tag, "doc") {
+			tag = strings.TrimSpace(tag + " doc:" + strconv.Quote(doc))
+		}
+		sfs = append(sfs, reflect.StructField{
+			Name:      v.Name(),
+			Type:      typ,
+			Tag:       reflect.StructTag(tag),
+			Anonymous: v.Embedded(),
+		})
+	}
+
+	typ := reflect.StructOf(sfs)
+	l.structs[key] = typ
+	return typ
+}
+
+// reflectType returns the stand-in reflect type for the Go type, if there is
+// one.  An optional.Value[T] stands in as a *T, which the helpers describe the
+// same way.
+func (l *loader) reflectType(typ types.Type) (reflect.Type, bool) {
+	if named, ok := typ.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == optionalPkg && obj.Name() == "Value" {
+			elem, ok := l.reflectType(named.TypeArgs().At(0))
+			if !ok {
+				return nil, false
+			}
+			return reflect.PointerTo(elem), true
+		}
+	}
+
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		kind, ok := basicKinds[t.Kind()]
+		return kind, ok
+	case *types.Pointer:
+		elem, ok := l.reflectType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return reflect.PointerTo(elem), true
+	case *types.Slice:
+		elem, ok := l.reflectType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return reflect.SliceOf(elem), true
+	case *types.Map:
+		key, ok := l.reflectType(t.Key())
+		if !ok {
+			return nil, false
+		}
+		elem, ok := l.reflectType(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return reflect.MapOf(key, elem), true
+	case *types.Struct:
+		return l.structType(typ.String(), t), true
+	}
+	return nil, false
+}
+
+var basicKinds = map[types.BasicKind]reflect.Type{
+	types.Bool:    reflect.TypeOf(false),
+	types.Int:     reflect.TypeOf(int(0)),
+	types.Int8:    reflect.TypeOf(int8(0)),
+	types.Int16:   reflect.TypeOf(int16(0)),
+	types.Int32:   reflect.TypeOf(int32(0)),
+	types.Int64:   reflect.TypeOf(int64(0)),
+	types.Uint:    reflect.TypeOf(uint(0)),
+	types.Uint8:   reflect.TypeOf(uint8(0)),
+	types.Uint16:  reflect.TypeOf(uint16(0)),
+	types.Uint32:  reflect.TypeOf(uint32(0)),
+	types.Uint64:  reflect.TypeOf(uint64(0)),
+	types.Float32: reflect.TypeOf(float32(0)),
+	types.Float64: reflect.TypeOf(float64(0)),
+	types.String:  reflect.TypeOf(""),
+}
+
+// collectDefaults records the constant field values in the composite
+// literal, including those of embedded structs, by Go path.
+func (l *loader) collectDefaults(lit *ast.CompositeLit, prefix string, defaults map[string]string) {
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		path := prefix + key.Name
+
+		if inner := compositeLiteral(kv.Value); inner != nil {
+			l.collectDefaults(inner, path+".", defaults)
+			continue
+		}
+		tv, ok := l.pkg.TypesInfo.Types[kv.Value]
+		if !ok || tv.Value == nil {
+			continue
+		}
+		if tv.Value.Kind() == constant.String {
+			defaults[path] = constant.StringVal(tv.Value)
+		} else {
+			defaults[path] = tv.Value.ExactString()
+		}
+	}
+}
+
+func (l *loader) stringArg(call *ast.CallExpr, i int) (string, bool) {
+	if i >= len(call.Args) {
+		return "", false
+	}
+	return l.constString(call.Args[i])
+}
+
+func (l *loader) constString(expr ast.Expr) (string, bool) {
+	tv, ok := l.pkg.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// isSimpleCommand reports whether the type is simple.Command.
+func isSimpleCommand(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == simplePkg && named.Obj().Name() == "Command"
+}
+
+func hasTag(tag string, key string) bool {
+	_, ok := reflect.StructTag(tag).Lookup(key)
+	return ok
+}
+
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
+	}
+}
+
+// compositeLiteral returns the literal in an expression like `&Params{...}` or
+// `Params{...}`, if there is one.
+func compositeLiteral(expr ast.Expr) *ast.CompositeLit {
+	if expr == nil {
+		return nil
+	}
+	expr = unparen(expr)
+	if unary, ok := expr.(*ast.UnaryExpr); ok {
+		expr = unparen(unary.X)
+	}
+	lit, _ := expr.(*ast.CompositeLit)
+	return lit
+}
+
+// returnedLiteral returns the literal returned by a constructor like
+// `func() *Params { return &Params{...} }`, if there is one.
+func returnedLiteral(expr ast.Expr) ast.Expr {
+	fn, ok := unparen(expr).(*ast.FuncLit)
+	if !ok {
+		return nil
+	}
+	for _, stmt := range fn.Body.List {
+		if ret, ok := stmt.(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+			return ret.Results[0]
+		}
+	}
+	return nil
+}
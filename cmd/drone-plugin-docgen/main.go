@@ -0,0 +1,113 @@
+// Command drone-plugin-docgen generates Markdown documentation for a plugin's
+// settings, from the params structs the plugin registers with the simple
+// package.  For each command, it writes an example `.drone.yml` step and a
+// table of the settings: name, type, default, whether it's required, and a
+// description (from the `doc` tag, or the field's Go doc comment).  It can
+// also write a JSON Schema for the settings instead (see the schema package),
+// for editors and linters.
+//
+// Usage:
+//
+//	drone-plugin-docgen [flags] [package]
+//
+// The package defaults to the one in the current directory.  The flags are:
+//
+//	-o file      write the documentation to file, rather than stdout
+//	-check       don't write anything, but fail if file (from -o) is stale
+//	-format fmt  "markdown" (the default) or "schema"
+//	-image name  the Docker image in the examples (default "plugins/<tool>")
+//	-type name   document the named params type, rather than looking for
+//	             the commands the package registers
+//
+// A typical use is a go:generate directive next to the plugin's main():
+//
+//	//go:generate drone-plugin-docgen -o DOCS.md
+//	//go:generate drone-plugin-docgen -format schema -o schema.json
+//
+// with the same commands (plus -check) in CI, so that the committed
+// documentation can't drift from the code.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errStale is returned by run() in -check mode when the docs are out of date.
+var errStale = errors.New("documentation is out of date; re-run drone-plugin-docgen")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "drone-plugin-docgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("drone-plugin-docgen", flag.ContinueOnError)
+	output := flags.String("o", "", "write the documentation to `file`")
+	check := flags.Bool("check", false, "fail if the documentation in -o is stale, rather than writing it")
+	image := flags.String("image", "", "the Docker `image` in the examples (default \"plugins/<tool>\")")
+	typeName := flags.String("type", "", "document the named params `type` only")
+	format := flags.String("format", "markdown", "the output `format`: \"markdown\" or \"schema\"")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	pattern := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		pattern = flags.Arg(0)
+	default:
+		return fmt.Errorf("expected at most one package, got %d", flags.NArg())
+	}
+	if *check && *output == "" {
+		return errors.New("-check requires -o")
+	}
+	if *format != "markdown" && *format != "schema" {
+		return fmt.Errorf("unknown format %q (expected \"markdown\" or \"schema\")", *format)
+	}
+
+	p, err := load(pattern, *typeName)
+	if err != nil {
+		return err
+	}
+	if *image == "" {
+		*image = "plugins/" + p.Tool
+	}
+
+	var docs []byte
+	if *format == "schema" {
+		docs, err = jsonSchema(p)
+	} else {
+		docs, err = markdown(p, *image)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *check:
+		existing, err := os.ReadFile(*output)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !bytes.Equal(existing, docs) {
+			return fmt.Errorf("%s: %w", *output, errStale)
+		}
+		return nil
+
+	case *output != "":
+		return os.WriteFile(*output, docs, 0644)
+	}
+
+	_, err = stdout.Write(docs)
+	return err
+}
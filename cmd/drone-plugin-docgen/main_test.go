@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testPackage = "./testdata/plugin"
+	testGolden  = "testdata/plugin/DOCS.md"
+)
+
+func TestRun(t *testing.T) {
+	examples := []struct {
+		format string
+		golden string
+	}{
+		{"markdown", testGolden},
+		{"schema", "testdata/plugin/schema.json"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.format, func(t *testing.T) {
+			expected, err := os.ReadFile(local.golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			err = run([]string{"-format", local.format, testPackage}, &b)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := b.String(); actual != string(expected) {
+				t.Errorf("expected %q, got %q", expected, actual)
+			}
+		})
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	stale := filepath.Join(t.TempDir(), "DOCS.md")
+	err := os.WriteFile(stale, []byte("# aws\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "DOCS.md")
+
+	examples := []struct {
+		name     string
+		output   string
+		expected error
+	}{
+		{"current", testGolden, nil},
+		{"stale", stale, errStale},
+		{"missing", missing, errStale},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := run([]string{"-check", "-o", local.output, testPackage}, &b)
+			if !errors.Is(err, local.expected) {
+				t.Errorf("expected %v, got %v", local.expected, err)
+			}
+			if b.Len() != 0 {
+				t.Errorf("expected no output, got %q", b.String())
+			}
+		})
+	}
+}
+
+func TestRunInvalidFlags(t *testing.T) {
+	examples := [][]string{
+		{"-check", testPackage},
+		{"-format", "html", testPackage},
+	}
+
+	for _, ex := range examples {
+		var b bytes.Buffer
+		err := run(ex, &b)
+		if err == nil {
+			t.Errorf("expected error for %q, got nil", ex)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/JaredReisinger/drone-plugin-helper/usage"
+)
+
+const generatedComment = "<!-- Code generated by drone-plugin-docgen. DO NOT EDIT. -->"
+
+// markdown renders the plugin documentation in the style of the
+// plugins.drone.io index: an example `.drone.yml` step, and a parameter
+// reference table, for each command.
+func markdown(p *plugin, image string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n\n", generatedComment)
+	fmt.Fprintf(&b, "# %s\n", p.Tool)
+
+	if p.Routed {
+		fmt.Fprintf(&b, "\nThe `command` setting chooses the `%s` command to run, either as a string (`command: %s`) or as a list.  The commands are:\n\n", p.Tool, p.Commands[0].Path)
+		for _, c := range p.Commands {
+			fmt.Fprintf(&b, "* [`%s %s`](#%s)\n", p.Tool, c.Path, anchor(p.Tool+" "+c.Path))
+		}
+	}
+
+	for _, c := range p.Commands {
+		u, err := usage.For(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.TypeName, err)
+		}
+		for _, s := range u.Settings {
+			s.Default = c.Defaults[s.Path]
+		}
+
+		level := "##"
+		if p.Routed {
+			fmt.Fprintf(&b, "\n## %s %s\n", p.Tool, c.Path)
+			level = "###"
+		}
+
+		fmt.Fprintf(&b, "\n%s Usage\n\n", level)
+		writeExample(&b, p, c, u, image)
+
+		fmt.Fprintf(&b, "\n%s Parameter Reference\n\n", level)
+		writeTable(&b, u)
+	}
+
+	return b.Bytes(), nil
+}
+
+// writeExample writes an example pipeline step, with the command (if any) and
+// any required settings.
+func writeExample(b *bytes.Buffer, p *plugin, c *command, u *usage.Usage, image string) {
+	name := p.Tool
+	if c.Path != "" {
+		name = strings.ReplaceAll(c.Path, " ", "-")
+	}
+
+	fmt.Fprintln(b, "```yaml")
+	fmt.Fprintln(b, "steps:")
+	fmt.Fprintf(b, "- name: %s\n", name)
+	fmt.Fprintf(b, "  image: %s\n", image)
+	fmt.Fprintln(b, "  settings:")
+	settings := 0
+	if c.Path != "" {
+		fmt.Fprintf(b, "    command: %s\n", c.Path)
+		settings++
+	}
+	for _, s := range u.Settings {
+		if s.Required || s.Default != "" {
+			fmt.Fprintf(b, "    %s: %s\n", s.Name, exampleValue(s))
+			settings++
+		}
+	}
+	if settings == 0 && len(u.Settings) > 0 {
+		fmt.Fprintf(b, "    %s: %s\n", u.Settings[0].Name, exampleValue(u.Settings[0]))
+	}
+	fmt.Fprintln(b, "```")
+}
+
+// exampleValue returns a plausible value for the setting: its default, or
+// one allowed by its validation rules, or one of the right type.
+func exampleValue(s *usage.Setting) string {
+	if s.Default != "" {
+		return yamlString(s.Default)
+	}
+	if rule, ok := s.Rules.Get("oneof"); ok {
+		return yamlString(strings.Split(rule.Arg, "|")[0])
+	}
+	if rule, ok := s.Rules.Get("min"); ok && strings.HasSuffix(s.Type, "integer") {
+		return rule.Arg
+	}
+
+	switch {
+	case s.Type == "boolean":
+		return "true"
+	case strings.HasSuffix(s.Type, "integer"):
+		return "1"
+	case strings.HasPrefix(s.Type, "list of "):
+		return "[ " + s.Name + "1, " + s.Name + "2 ]"
+	}
+	return s.Name
+}
+
+// yamlString quotes the value if YAML wouldn't read it as a plain string.
+func yamlString(value string) string {
+	if value == "" || strings.ContainsAny(value, ":#{}[],&*!|>'\"%@`") || strings.TrimSpace(value) != value {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
+
+// writeTable writes the settings as a Markdown table.
+func writeTable(b *bytes.Buffer, u *usage.Usage) {
+	if len(u.Settings) == 0 {
+		fmt.Fprintln(b, "This command has no settings.")
+		return
+	}
+
+	fmt.Fprintln(b, "| Setting | Type | Default | Required | Flag | Description |")
+	fmt.Fprintln(b, "|---------|------|---------|----------|------|-------------|")
+	for _, s := range u.Settings {
+		required := "no"
+		if s.Required {
+			required = "yes"
+		}
+		defaultValue := ""
+		if s.Default != "" {
+			defaultValue = "`" + s.Default + "`"
+		}
+		flag := s.FlagText()
+		if strings.HasPrefix(flag, "-") {
+			flag = "`" + flag + "`"
+		}
+		description := s.Description
+		if constraints := s.Constraints(); len(constraints) > 0 {
+			description = strings.TrimSpace(fmt.Sprintf("%s (%s)", description, strings.Join(constraints, "; ")))
+		}
+		fmt.Fprintf(b, "| `%s` | %s | %s | %s | %s | %s |\n", s.Name, s.Type, defaultValue, required, flag, escapeCell(description))
+	}
+}
+
+// escapeCell keeps the text from breaking out of a table cell.
+func escapeCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// anchor returns the GitHub-style anchor for a heading.
+func anchor(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ' || r == '-':
+			b.WriteRune('-')
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
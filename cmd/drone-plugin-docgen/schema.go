@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/JaredReisinger/drone-plugin-helper/schema"
+	"github.com/JaredReisinger/drone-plugin-helper/usage"
+)
+
+// jsonSchema renders the plugin settings as JSON Schema (see the schema
+// package).
+func jsonSchema(p *plugin) ([]byte, error) {
+	usages := make(map[string]*usage.Usage)
+	for _, c := range p.Commands {
+		u, err := usage.For(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.TypeName, err)
+		}
+		for _, s := range u.Settings {
+			s.Default = c.Defaults[s.Path]
+		}
+		usages[c.Path] = u
+	}
+
+	var s *schema.Schema
+	if p.Routed {
+		s = schema.Commands(usages)
+	} else {
+		s = schema.FromUsage(usages[""])
+	}
+	s.Title = p.Tool
+
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,42 @@
+// This is a small plugin for testing drone-plugin-docgen.
+package main
+
+import (
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+	"github.com/JaredReisinger/drone-plugin-helper/simple"
+)
+
+// GlobalParams are shared by every command.
+type GlobalParams struct {
+	simple.Command
+
+	// Debug enables verbose output.
+	Debug   bool
+	Profile string `doc:"the named profile to use" validate:"oneof=default|staging|production"`
+}
+
+// CopyParams are the options for "aws s3 cp".
+type CopyParams struct {
+	GlobalParams
+	Recursive bool                // copy all files under the source
+	Exclude   []string            // patterns to exclude
+	Retries   optional.Value[int] `validate:"min=0,max=10"`
+	Source    string              `cmd:",positional" validate:"required"`
+	Target    string              `cmd:",positional" validate:"required"`
+}
+
+// ListParams are the options for "aws s3 ls".
+type ListParams struct {
+	GlobalParams
+	Summarize bool
+	PageSize  int `cmd:"--page-size" doc:"the number of results per call"`
+}
+
+func main() {
+	r := &simple.Registry{}
+	simple.Register[CopyParams](r, "s3 cp")
+	simple.RegisterFunc(r, "s3 ls", func() *ListParams {
+		return &ListParams{PageSize: 1000, GlobalParams: GlobalParams{Profile: "default"}}
+	})
+	r.Exec("aws")
+}
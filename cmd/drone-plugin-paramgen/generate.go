@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+)
+
+// numericPlaceholders are the upper-case placeholders (as in GNU and argparse
+// help) that stand for whole numbers.
+var numericPlaceholders = map[string]bool{
+	"N": true, "NUM": true, "NUMBER": true, "COUNT": true, "INT": true, "INTEGER": true,
+	"PORT": true, "DEPTH": true, "LINES": true, "COLS": true, "COLUMNS": true,
+	"SECONDS": true, "SECS": true, "RETRIES": true, "JOBS": true,
+}
+
+// pflagTypes are the type placeholders that pflag (and Go's flag package)
+// print, and the field types for them.  Floats and durations are left as
+// strings, since the env package doesn't parse them.
+var pflagTypes = map[string]string{
+	"bool": "bool", "count": "bool",
+	"int": "int", "int8": "int8", "int16": "int16", "int32": "int32", "int64": "int64",
+	"uint": "uint", "uint8": "uint8", "uint16": "uint16", "uint32": "uint32", "uint64": "uint64",
+	"stringArray": "[]string", "stringSlice": "[]string", "strings": "[]string", "stringToString": "[]string",
+	"ints": "[]int", "intSlice": "[]int", "int32Slice": "[]int32", "int64Slice": "[]int64",
+	"uints": "[]uint", "uintSlice": "[]uint", "bools": "[]bool", "boolSlice": "[]bool",
+}
+
+// optionalImport is the package for the optional.Value of a negatable flag.
+const optionalImport = "github.com/JaredReisinger/drone-plugin-helper/optional"
+
+// generator writes the params struct for the options.
+type generator struct {
+	Package    string   // the package clause
+	TypeName   string   // the struct type
+	Title      string   // what the options are for, like `"helm install"`
+	Source     string   // where the help text came from, for the header
+	Embed      []string // types to embed at the top of the struct
+	SkipGlobal bool     // leave out global flags (which are likely embedded)
+
+	names    *names.Set
+	seen     map[string]string // field name => option
+	optional bool              // a field is an optional.Value, so the import is needed
+}
+
+// generate returns the (formatted) Go source for the options.
+func (g *generator) generate(opts []*option) ([]byte, error) {
+	g.names = names.Default()
+	g.seen = make(map[string]string)
+	g.optional = false
+
+	// positionals go last, in order, since that's where they'll be on the
+	// command-line
+	var body bytes.Buffer
+	for _, positional := range []bool{false, true} {
+		for _, o := range opts {
+			if o.Positional != positional || isHelp(o) || (o.Global && g.SkipGlobal) {
+				continue
+			}
+			g.writeField(&body, o)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Generated by drone-plugin-paramgen from %s; review the\n", g.Source)
+	fmt.Fprintf(&b, "// types, and add `validate`, `doc` and `secret` tags as needed.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", g.Package)
+	if g.optional {
+		fmt.Fprintf(&b, "import %q\n\n", optionalImport)
+	}
+	fmt.Fprintf(&b, "// %s are the options for %s\n", g.TypeName, g.Title)
+	fmt.Fprintf(&b, "type %s struct {\n", g.TypeName)
+	for _, embed := range g.Embed {
+		fmt.Fprintf(&b, "%s\n", embed)
+	}
+	b.Write(body.Bytes())
+	fmt.Fprintln(&b, "}")
+
+	return format.Source(b.Bytes())
+}
+
+// writeField writes a single field, with its description as a doc comment.
+func (g *generator) writeField(b *bytes.Buffer, o *option) {
+	name, flag := g.fieldName(o)
+	if other, ok := g.seen[name]; ok {
+		fmt.Fprintf(b, "// skipped %s, since its field name is the same as %s's\n", o.Names[0], other)
+		return
+	}
+	g.seen[name] = o.Names[0]
+
+	for _, line := range wrap(o.Description, 72) {
+		fmt.Fprintf(b, "// %s\n", line)
+	}
+
+	var cmdTag []string
+	switch {
+	case o.Positional:
+		cmdTag = []string{"", "positional"}
+	case flag != "":
+		cmdTag = []string{flag}
+	}
+	if o.Negatable {
+		if cmdTag == nil {
+			cmdTag = []string{""}
+		}
+		cmdTag = append(cmdTag, "no")
+	}
+
+	var tags []string
+	if cmdTag != nil {
+		tags = append(tags, fmt.Sprintf("cmd:%q", strings.Join(cmdTag, ",")))
+	}
+	if o.Choices != nil {
+		tags = append(tags, fmt.Sprintf("validate:%q", "oneof="+strings.Join(o.Choices, "|")))
+	}
+
+	typ := goType(o)
+	if strings.HasPrefix(typ, "optional.") {
+		g.optional = true
+	}
+	if len(tags) > 0 {
+		fmt.Fprintf(b, "%s %s `%s`\n", name, typ, strings.Join(tags, " "))
+	} else {
+		fmt.Fprintf(b, "%s %s\n", name, typ)
+	}
+}
+
+// fieldName returns the field name for the option, and the flag for its `cmd`
+// tag, if the flag can't be derived from the field name: short flags, long
+// flags with a single dash (as with Go's flag package), and names that don't
+// round-trip, like "--dry_run".
+func (g *generator) fieldName(o *option) (name string, flag string) {
+	if o.Positional {
+		name = g.pascal(o.Names[0])
+		if o.List && !strings.HasSuffix(name, "s") {
+			name += "s"
+		}
+		return
+	}
+
+	flag = o.Names[0]
+	for _, n := range o.Names {
+		if strings.HasPrefix(n, "--") {
+			flag = n
+			break
+		}
+		if len(n) > len(flag) {
+			flag = n
+		}
+	}
+
+	name = g.pascal(strings.TrimLeft(flag, "-"))
+	if strings.HasPrefix(flag, "--") {
+		if derived, err := g.names.Convert(name, names.Kebab); err == nil && "--"+derived == flag {
+			flag = ""
+		}
+	}
+	return
+}
+
+// pascal turns a flag or argument name (like "tls-ca-cert", "dry_run" or
+// "RELEASE_NAME") into an exported field name, with initialisms in upper-case
+// ("TLSCaCert", unless "CA" is registered with the names package).
+func (g *generator) pascal(flag string) string {
+	var b strings.Builder
+	words := strings.FieldsFunc(flag, func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	for _, word := range words {
+		upper := strings.ToUpper(word)
+		switch {
+		case g.names.IsInitialism(upper):
+			b.WriteString(upper)
+		case g.names.IsPluralInitialism(upper):
+			b.WriteString(upper[:len(upper)-1] + "s")
+		default:
+			lower := []rune(strings.ToLower(word))
+			lower[0] = unicode.ToUpper(lower[0])
+			b.WriteString(string(lower))
+		}
+	}
+
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "Flag" + name
+	}
+	return name
+}
+
+// goType infers the field type from the option's value placeholder.
+func goType(o *option) string {
+	if o.Positional {
+		if o.List {
+			return "[]string"
+		}
+		return "string"
+	}
+	if o.Negatable {
+		// a flag with a "--no-" form has three states: given, negated, or
+		// left to the tool's own default
+		return "optional.Value[bool]"
+	}
+	if o.Optional || (o.Placeholder == "" && o.Choices == nil) {
+		// a flag with an optional value can only be given on its own, since
+		// the value would have to be attached ("--color=always")
+		return "bool"
+	}
+
+	typ, ok := pflagTypes[o.Placeholder]
+	switch {
+	case ok:
+	case numericPlaceholders[o.Placeholder]:
+		typ = "int"
+	case isCustomList(o.Placeholder):
+		typ = "[]string"
+	default:
+		typ = "string"
+	}
+	if o.List && !strings.HasPrefix(typ, "[]") {
+		typ = "[]" + typ
+	}
+	return typ
+}
+
+// isCustomList reports whether the placeholder looks like the name of a
+// custom pflag list type, like helm's "valueFiles".
+func isCustomList(placeholder string) bool {
+	if placeholder == "" || !unicode.IsLower([]rune(placeholder)[0]) {
+		return false
+	}
+	return strings.HasSuffix(placeholder, "Array") || strings.HasSuffix(placeholder, "Slice") || strings.HasSuffix(placeholder, "s")
+}
+
+// isHelp reports whether the option just asks for help, which a params struct
+// has no use for.
+func isHelp(o *option) bool {
+	for _, name := range o.Names {
+		switch name {
+		case "-h", "--help", "-help", "-?":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// wrap splits the text into lines of at most width characters (unless a
+// single word is longer).
+func wrap(text string, width int) (lines []string) {
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return
+}
@@ -0,0 +1,148 @@
+// Command drone-plugin-paramgen generates a params struct from a tool's
+// `--help` output, as a starting point for wrapping the tool in a plugin.  It
+// understands the common ways of listing options (cobra/pflag, GNU getopt, Go's
+// flag package, and Python's argparse), and writes a field for each one, with
+// its description as a doc comment.  Field types are inferred from the value
+// placeholders ("string", "int", "stringArray", "COUNT", "FILE...") and `cmd`
+// tags are added where the flag can't be derived from the field name, like
+// short flags ("-v") and single-dash or snake_case names ("-kubeconfig",
+// "--dry_run").  A flag with a "--no-" form becomes an optional.Value[bool]
+// with the `no` option, so that neither form is passed unless the setting is
+// given.  Positional arguments come last.
+//
+// Usage:
+//
+//	drone-plugin-paramgen [flags] [help-file]
+//
+// The help text is read from help-file ("-" for stdin), or else by running
+// the -command with `--help`.  The flags are:
+//
+//	-command "tool sub"  the command the help is for; run with --help if
+//	                     there's no help-file
+//	-type name           the struct type (default from the command, like
+//	                     "InstallParams" for "helm install")
+//	-package name        the package clause (default "main")
+//	-embed names         comma-separated types to embed, like "GlobalParams"
+//	-skip-global         leave out global flags (which are likely embedded)
+//	-o file              write the struct to file, rather than stdout
+//
+// For example, with the output of `helm install --help` saved:
+//
+//	drone-plugin-paramgen -command "helm install" -embed GlobalParams -skip-global install.txt
+//
+// The generated struct is a draft: check the types, and add any `validate`
+// and `doc` tags, and `secret` options, that it needs.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "drone-plugin-paramgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("drone-plugin-paramgen", flag.ContinueOnError)
+	command := flags.String("command", "", "the `command` the help is for, like \"helm install\"")
+	typeName := flags.String("type", "", "the struct `type` (default from the command)")
+	pkg := flags.String("package", "main", "the `package` clause")
+	embed := flags.String("embed", "", "comma-separated `types` to embed")
+	skipGlobal := flags.Bool("skip-global", false, "leave out global flags")
+	output := flags.String("o", "", "write the struct to `file`")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	words := strings.Fields(*command)
+	g := &generator{
+		Package:    *pkg,
+		TypeName:   *typeName,
+		Title:      fmt.Sprintf("%q", *command),
+		SkipGlobal: *skipGlobal,
+	}
+	if g.TypeName == "" {
+		g.TypeName = defaultTypeName(words)
+	}
+	for _, name := range strings.Split(*embed, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			g.Embed = append(g.Embed, name)
+		}
+	}
+
+	var help []byte
+	switch {
+	case flags.NArg() > 1:
+		return fmt.Errorf("expected at most one help file, got %d", flags.NArg())
+	case flags.NArg() == 1 && flags.Arg(0) == "-":
+		g.Source = "stdin"
+		help, err = io.ReadAll(stdin)
+	case flags.NArg() == 1:
+		g.Source = fmt.Sprintf("%q", flags.Arg(0))
+		help, err = os.ReadFile(flags.Arg(0))
+	case len(words) > 0:
+		g.Source = fmt.Sprintf("`%s --help`", *command)
+		help, err = runHelp(words)
+	default:
+		return errors.New("expected a help file or -command")
+	}
+	if err != nil {
+		return err
+	}
+	if *command == "" {
+		g.Title = g.Source
+	}
+
+	opts := parseHelp(string(help))
+	if len(opts) == 0 {
+		return errors.New("no options found in the help text")
+	}
+
+	source, err := g.generate(opts)
+	if err != nil {
+		return err
+	}
+
+	if *output != "" {
+		return os.WriteFile(*output, source, 0644)
+	}
+	_, err = stdout.Write(source)
+	return err
+}
+
+// runHelp runs the command with `--help`.  Many tools exit with an error
+// after showing help, so that's only a failure if there's no output.
+func runHelp(words []string) ([]byte, error) {
+	help, err := exec.Command(words[0], append(words[1:], "--help")...).CombinedOutput()
+	if len(help) == 0 && err != nil {
+		return nil, fmt.Errorf("unable to run %q: %w", strings.Join(words, " "), err)
+	}
+	return help, nil
+}
+
+// defaultTypeName names the struct for the subcommand words, like
+// "DependencyBuildParams" for "helm dependency build".
+func defaultTypeName(words []string) string {
+	if len(words) > 0 {
+		words = words[1:]
+	}
+
+	var b strings.Builder
+	for _, word := range words {
+		for _, part := range strings.FieldsFunc(word, func(r rune) bool { return r == '-' || r == '_' }) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String() + "Params"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	examples := []struct {
+		fixture string
+		args    []string
+	}{
+		{"helm-install", []string{"-command", "helm install", "-embed", "GlobalParams", "-skip-global"}},
+		{"ls", []string{"-command", "ls"}},
+		{"go-flag", []string{"-type", "LintParams"}},
+		{"argparse", []string{"-command", "deploy.py", "-package", "deploy"}},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.fixture, func(t *testing.T) {
+			expected, err := os.ReadFile("testdata/" + local.fixture + ".golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			err = run(append(local.args, "testdata/"+local.fixture+".txt"), nil, &b)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := b.String(); actual != string(expected) {
+				t.Errorf("expected %q, got %q", expected, actual)
+			}
+		})
+	}
+}
+
+func TestRunStdin(t *testing.T) {
+	help := "Flags:\n      --wait   wait for it\n"
+
+	var b bytes.Buffer
+	err := run([]string{"-type", "WaitParams", "-"}, strings.NewReader(help), &b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "\tWait bool\n"; !strings.Contains(b.String(), expected) {
+		t.Errorf("expected %q in %q", expected, b.String())
+	}
+}
+
+func TestRunNegatable(t *testing.T) {
+	help := "Flags:\n      --wait      wait for it\n      --no-wait   don't wait\n      --name string  the name\n"
+
+	var b bytes.Buffer
+	err := run([]string{"-type", "WaitParams", "-"}, strings.NewReader(help), &b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"import \"github.com/JaredReisinger/drone-plugin-helper/optional\"\n",
+		"\tWait optional.Value[bool] `cmd:\",no\"`\n",
+	} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("expected %q in %q", expected, b.String())
+		}
+	}
+
+	// a negatable flag that is left out doesn't need the import
+	b.Reset()
+	help = "Flags:\n      --name string  the name\nGlobal Flags:\n      --wait      wait for it\n      --no-wait   don't wait\n"
+	err = run([]string{"-type", "NameParams", "-skip-global", "-"}, strings.NewReader(help), &b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(b.String(), "import") {
+		t.Errorf("expected no import in %q", b.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	examples := []struct {
+		name string
+		args []string
+	}{
+		{"no input", []string{}},
+		{"no options", []string{"-"}},
+		{"two files", []string{"a.txt", "b.txt"}},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := run(local.args, strings.NewReader("nothing to see here\n"), &b)
+			if err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestGoType(t *testing.T) {
+	examples := []struct {
+		spec     string
+		expected string
+	}{
+		{"--wait", "bool"},
+		{"--name string", "string"},
+		{"--timeout int", "int"},
+		{"--revision int32", "int32"},
+		{"--set stringArray", "[]string"},
+		{"--values valueFiles", "[]string"},
+		{"--ports ints", "[]int"},
+		{"--timeout duration", "string"},
+		{"--width=COLS", "int"},
+		{"--ignore=PATTERN", "string"},
+		{"--color[=WHEN]", "bool"},
+		{"--color string[=\"always\"]", "bool"},
+		{"--tag TAG [TAG ...]", "[]string"},
+		{"--retries N", "int"},
+		{"--mode {fast,safe}", "string"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.spec, func(t *testing.T) {
+			actual := goType(parseOption(local.spec))
+			if actual != local.expected {
+				t.Errorf("expected %q, got %q", local.expected, actual)
+			}
+		})
+	}
+}
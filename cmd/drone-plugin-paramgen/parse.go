@@ -0,0 +1,209 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxOptionIndent is the deepest an option can be indented; anything deeper
+// is the continuation of a description.
+const maxOptionIndent = 8
+
+var (
+	specEnd     = regexp.MustCompile(`\t|\s{2,}`)
+	alternative = regexp.MustCompile(`,\s+`)
+	optionName  = regexp.MustCompile(`^(-{1,2}[A-Za-z0-9?][\w.?-]*)(.*)$`)
+	positional  = regexp.MustCompile(`^[a-zA-Z][\w-]*$`)
+)
+
+// option is a single option (or positional argument) from the help text.
+type option struct {
+	Names       []string // as listed, like "-n" and "--namespace"
+	Placeholder string   // the value placeholder, like "string" or "FILE"; empty for a boolean flag
+	Choices     []string // the allowed values, from "{a,b,c}"
+	List        bool     // several values may be given, like "FILE..." or "[FOO ...]"
+	Optional    bool     // the value is optional, like "--color[=WHEN]"
+	Negatable   bool     // there is also a "--no-" form of the flag
+	Positional  bool     // a positional argument, rather than a flag
+	Global      bool     // listed under global flags (like cobra's "Global Flags:")
+	Description string
+}
+
+// parseHelp finds the options in the help text.  It understands the common
+// layouts, which all list one option per line, with the description after
+// it (or on the following lines):
+//
+//	cobra/pflag:  "  -n, --namespace string   namespace scope (default "x")"
+//	GNU getopt:   "  -w, --width=COLS         set output width to COLS"
+//	Go flag:      "  -kubeconfig string" + "    \tpath to the kubeconfig"
+//	argparse:     "  -n N, --num N            the number" (and "{a,b}" choices)
+//
+// Positional arguments come from argparse's "positional arguments:" section,
+// or else from the upper-case arguments in the usage line, like
+// "Usage: helm install [CHART] [flags]".
+func parseHelp(text string) (opts []*option) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	section := ""
+	usage := ""
+	sawPositionals := false
+	var current *option
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		switch {
+		case trimmed == "":
+			current = nil
+
+		case indent == 0:
+			current = nil
+			section = ""
+			if strings.HasSuffix(trimmed, ":") {
+				section = strings.ToLower(strings.TrimSuffix(trimmed, ":"))
+			}
+			if usage == "" && strings.HasPrefix(strings.ToLower(trimmed), "usage:") {
+				usage = strings.TrimSpace(trimmed[len("usage:"):])
+				if usage == "" && i+1 < len(lines) {
+					usage = strings.TrimSpace(lines[i+1])
+				}
+			}
+
+		case indent <= maxOptionIndent && optionName.MatchString(trimmed):
+			current = parseOption(trimmed)
+			current.Global = strings.Contains(section, "global")
+			opts = append(opts, current)
+
+		case indent <= maxOptionIndent && section == "positional arguments":
+			sawPositionals = true
+			name, description, _ := strings.Cut(trimmed, " ")
+			if !positional.MatchString(name) {
+				current = nil
+				continue
+			}
+			current = &option{Names: []string{name}, Positional: true, Description: strings.TrimSpace(description)}
+			opts = append(opts, current)
+
+		case current != nil:
+			current.Description = strings.TrimSpace(current.Description + " " + trimmed)
+		}
+	}
+
+	if !sawPositionals {
+		opts = append(opts, usagePositionals(usage)...)
+	}
+	return mergeNegations(opts)
+}
+
+// parseOption parses a single option line: the names and value placeholder,
+// then the description (if any) after a tab or a run of spaces.
+func parseOption(line string) *option {
+	spec, description := line, ""
+	if loc := specEnd.FindStringIndex(line); loc != nil {
+		spec, description = line[:loc[0]], strings.TrimSpace(line[loc[1]:])
+	}
+
+	o := &option{Description: description}
+	for _, alt := range alternative.Split(spec, -1) {
+		m := optionName.FindStringSubmatch(alt)
+		if m == nil {
+			continue
+		}
+		o.Names = append(o.Names, m[1])
+		if o.Placeholder == "" && o.Choices == nil {
+			o.parseValue(strings.TrimSpace(m[2]))
+		}
+	}
+	return o
+}
+
+// parseValue parses the value placeholder after an option name, like
+// " string", "=SIZE", "[=WHEN]", " FOO [FOO ...]" or " {fast,slow}".
+func (o *option) parseValue(value string) {
+	if strings.HasPrefix(value, "[=") {
+		o.Optional = true
+	}
+	if i := strings.Index(value, "[="); i > 0 {
+		// pflag's NoOptDefVal, like `string[="always"]`
+		o.Optional = true
+		value = value[:i]
+	}
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "[="), "=")
+	if value == "" {
+		return
+	}
+
+	o.List = strings.Contains(value, "...")
+	value = strings.Trim(strings.Fields(value)[0], "[]<>.")
+	if strings.HasPrefix(value, "{") {
+		o.Choices = strings.Split(strings.Trim(value, "{}"), ",")
+		return
+	}
+	o.Placeholder = value
+}
+
+// usagePositionals finds the positional arguments in a usage line: the
+// upper-case (or <bracketed>) arguments after the command words, like "CHART"
+// in "helm install [CHART] [flags]", or "FILE" in "ls [OPTION]... [FILE]...".
+func usagePositionals(usage string) (opts []*option) {
+	words := strings.Fields(usage)
+	for len(words) > 0 && positional.MatchString(words[0]) && strings.ToUpper(words[0]) != words[0] {
+		words = words[1:]
+	}
+
+	for _, word := range words {
+		bracketed := strings.Contains(word, "<")
+		name := strings.Trim(word, "[]<>.")
+		switch {
+		case name == "" || strings.HasPrefix(name, "-"):
+			continue
+		case strings.EqualFold(name, "flags") || strings.EqualFold(name, "options") || strings.EqualFold(name, "option") || strings.EqualFold(name, "command"):
+			continue
+		case !positional.MatchString(name) || (!bracketed && strings.ToUpper(name) != name):
+			continue
+		}
+		opts = append(opts, &option{Names: []string{name}, Positional: true, List: strings.Contains(word, "...")})
+	}
+	return
+}
+
+// mergeNegations folds "--no-" flags into the flags they negate, whether they
+// are listed together (argparse's "--foo, --no-foo") or separately.  A "--no-"
+// flag without a positive form is left alone, like helm's "--no-hooks".
+func mergeNegations(opts []*option) (merged []*option) {
+	flags := make(map[string]*option)
+	for _, o := range opts {
+		if o.Positional || o.Placeholder != "" || o.Choices != nil {
+			continue
+		}
+		for _, name := range o.Names {
+			flags[name] = o
+		}
+	}
+
+	for _, o := range opts {
+		var names []string
+		for _, name := range o.Names {
+			positive, ok := negates(name)
+			if ok && flags[positive] != nil {
+				flags[positive].Negatable = true
+				continue
+			}
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		o.Names = names
+		merged = append(merged, o)
+	}
+	return
+}
+
+// negates returns the flag that a "--no-" flag negates.
+func negates(name string) (string, bool) {
+	if !strings.HasPrefix(name, "--no-") {
+		return "", false
+	}
+	return "--" + strings.TrimPrefix(name, "--no-"), true
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+)
+
+// Naming describes how flag names are derived from field names (for fields
+// without an explicit flag in their `cmd` tag): the field name is split into
+// words (see names.Split()), and the words are joined using the Convention,
+// after the Prefix.  A negated bool flag (see the `no` tag option) is derived
+// the same way, with "no" as the first word.  The zero Naming is the
+// traditional "--kebab-case".
+type Naming struct {
+	Prefix     string           // the flag prefix; "" means "--"
+	Convention names.Convention // how the words are joined; nil means names.Kebab
+}
+
+var (
+	// KebabNaming derives flags like "--tiller-connection-timeout".
+	KebabNaming = Naming{Prefix: "--", Convention: names.Kebab}
+
+	// SnakeNaming derives flags like "--tiller_connection_timeout".
+	SnakeNaming = Naming{Prefix: "--", Convention: names.Snake}
+
+	// CamelNaming derives flags like "--tillerConnectionTimeout".
+	CamelNaming = Naming{Prefix: "--", Convention: names.Camel}
+
+	// PascalNaming derives flags like "--TillerConnectionTimeout".
+	PascalNaming = Naming{Prefix: "--", Convention: names.Pascal}
+
+	// DotNaming derives flags like "--tiller.connection.timeout".
+	DotNaming = Naming{Prefix: "--", Convention: names.Dot}
+
+	// ScreamingSnakeNaming derives flags like "--TILLER_CONNECTION_TIMEOUT".
+	ScreamingSnakeNaming = Naming{Prefix: "--", Convention: names.ScreamingSnake}
+)
+
+func (n Naming) withDefaults() Naming {
+	if n.Prefix == "" {
+		n.Prefix = "--"
+	}
+	if n.Convention == nil {
+		n.Convention = names.Kebab
+	}
+	return n
+}
+
+// flag derives the flag for the words of a field name.
+func (n Naming) flag(words []string) string {
+	return n.Prefix + n.Convention.Join(words)
+}
+
+// FlagNamer can be implemented by a params struct (with a pointer receiver, or
+// a value receiver) to choose the naming of its own flags, overriding the
+// Encoder's naming.  The method is called on the zero value of the struct, and
+// the result is cached, so it must not depend on the struct's contents.  The
+// naming applies to all of the struct's fields, including those of any inner
+// or embedded structs, unless they implement FlagNamer themselves.
+type FlagNamer interface {
+	FlagNaming() Naming
+}
+
+var flagNamerType = reflect.TypeOf((*FlagNamer)(nil)).Elem()
+
+// Encoder creates command-lines from params structs (and parses them back),
+// like Create() and Parse(), with a configurable naming for the flags.  The
+// zero Encoder behaves exactly like the package-level functions.
+//
+// An Encoder is safe for concurrent use, so long as it isn't modified.
+type Encoder struct {
+	// Naming derives flag names from field names; the zero Naming is
+	// KebabNaming.  A params struct implementing FlagNamer overrides it.
+	Naming Naming
+
+	// Names are the initialisms used to split field names into words; nil
+	// means names.Default().
+	Names *names.Set
+
+	// TrueWords and FalseWords are the (case-insensitive) words Parse()
+	// accepts for a bool flag's value, like "--debug=on"; nil means
+	// env.DefaultTrueWords and env.DefaultFalseWords, as with an env.Decoder.
+	TrueWords  []string
+	FalseWords []string
+}
+
+func (e *Encoder) names() *names.Set {
+	if e.Names == nil {
+		return names.Default()
+	}
+	return e.Names
+}
+
+// naming returns the Encoder's naming, which is part of the key for cached
+// plans, and so must be comparable (unlike, say, a func-backed Convention).
+func (e *Encoder) naming() (Naming, error) {
+	naming := e.Naming.withDefaults()
+	if !reflect.ValueOf(naming.Convention).Comparable() {
+		return naming, fmt.Errorf("naming convention %T is not comparable, so it can't be used by an Encoder (a FlagNamer can return it instead)", naming.Convention)
+	}
+	return naming, nil
+}
+
+// namingFor returns the naming for the field of the root struct type: that of
+// the innermost struct along its path (including the root) that implements
+// FlagNamer, otherwise the given naming.
+func namingFor(root reflect.Type, f *fields.Field, naming Naming) Naming {
+	typ := fields.TypeIndirect(root)
+	for i, index := range f.Index {
+		if own, ok := flagNaming(typ); ok {
+			naming = own
+		}
+		if i < len(f.Index)-1 {
+			typ = fields.TypeIndirect(typ.Field(index).Type)
+		}
+	}
+	return naming
+}
+
+// flagNaming returns the struct type's own naming, if it implements FlagNamer.
+func flagNaming(typ reflect.Type) (Naming, bool) {
+	if !reflect.PointerTo(typ).Implements(flagNamerType) {
+		return Naming{}, false
+	}
+	namer := reflect.New(typ).Interface().(FlagNamer)
+	return namer.FlagNaming().withDefaults(), true
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+)
+
+type namingParams struct {
+	TillerNamespace string
+	TLSCertID       string `cmd:"--cert-id"`
+	Wait            bool   `cmd:",no"`
+}
+
+func TestEncoderNaming(t *testing.T) {
+	params := &namingParams{TillerNamespace: "kube-system", TLSCertID: "abc", Wait: false}
+
+	examples := []struct {
+		naming   Naming
+		expected []string
+	}{
+		{Naming{}, []string{"--tiller-namespace", "kube-system", "--cert-id", "abc", "--no-wait"}},
+		{SnakeNaming, []string{"--tiller_namespace", "kube-system", "--cert-id", "abc", "--no_wait"}},
+		{CamelNaming, []string{"--tillerNamespace", "kube-system", "--cert-id", "abc", "--noWait"}},
+		{PascalNaming, []string{"--TillerNamespace", "kube-system", "--cert-id", "abc", "--NoWait"}},
+		{DotNaming, []string{"--tiller.namespace", "kube-system", "--cert-id", "abc", "--no.wait"}},
+		{ScreamingSnakeNaming, []string{"--TILLER_NAMESPACE", "kube-system", "--cert-id", "abc", "--NO_WAIT"}},
+		{Naming{Prefix: "-", Convention: names.Camel}, []string{"-tillerNamespace", "kube-system", "--cert-id", "abc", "-noWait"}},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.naming.withDefaults().Convention.(names.Case).String(), func(t *testing.T) {
+			e := &Encoder{Naming: local.naming}
+			actual, err := e.Create(params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, local.expected) {
+				t.Errorf("expected %q, got %q", local.expected, actual)
+			}
+
+			parsed := &namingParams{Wait: true}
+			err = e.Parse(actual, parsed)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(parsed, params) {
+				t.Errorf("expected %+v, got %+v", params, parsed)
+			}
+		})
+	}
+}
+
+type snakeParams struct {
+	MaxHistory int
+}
+
+func (*snakeParams) FlagNaming() Naming {
+	return SnakeNaming
+}
+
+func TestFlagNamer(t *testing.T) {
+	// the struct's own naming wins over the Encoder's
+	for _, e := range []*Encoder{{}, {Naming: CamelNaming}} {
+		actual, err := e.Create(snakeParams{MaxHistory: 5})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"--max_history", "5"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+}
+
+type nestedNamerParams struct {
+	DryRun  bool
+	History snakeParams // not embedded, which would promote its FlagNaming()
+}
+
+func TestFlagNamerNested(t *testing.T) {
+	// an inner struct's own naming applies to its fields only
+	actual, err := Create(nestedNamerParams{DryRun: true, History: snakeParams{MaxHistory: 5}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"--dry-run", "--max_history", "5"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+// funcConvention is a Convention that isn't comparable.
+type funcConvention func(words []string) string
+
+func (f funcConvention) Join(words []string) string { return f(words) }
+
+type funcNamerParams struct {
+	MaxHistory int
+}
+
+func (funcNamerParams) FlagNaming() Naming {
+	return Naming{Prefix: "-", Convention: funcConvention(func(words []string) string { return strings.Join(words, "") })}
+}
+
+func TestNamingNotComparable(t *testing.T) {
+	e := &Encoder{Naming: Naming{Convention: funcConvention(func(words []string) string { return "x" })}}
+	_, err := e.Create(snakeParams{MaxHistory: 5})
+	if err == nil {
+		t.Error("missing expected error")
+	}
+
+	// a FlagNamer's naming isn't cached by itself, so it may be anything
+	actual, err := Create(funcNamerParams{MaxHistory: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"-MaxHistory", "5"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestExecuteWithEncoder(t *testing.T) {
+	var b strings.Builder
+	err := Execute("helm", &namingParams{TillerNamespace: "ns"}, WithEncoder(&Encoder{Naming: SnakeNaming}), DryRun(&b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "helm --tiller_namespace ns --no_wait"
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if actual := lines[len(lines)-1]; actual != expected {
+		t.Errorf("expected command %q, got %q", expected, actual)
+	}
+}
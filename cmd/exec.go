@@ -1,49 +1,249 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 	// "github.com/JaredReisinger/drone-plugin-helper/env"
 )
 
+var (
+	// exit is swapped out by tests so that Main() can be exercised.
+	exit = os.Exit
+
+	envNameRe = regexp.MustCompile(`^[[:alpha:]_][[:alnum:]_]*$`)
+)
+
+// ExitError is returned when a command runs, but exits with a non-zero status.
+type ExitError struct {
+	Command string // the command that was run
+	Code    int    // the exit status of the command
+	Err     error  // the underlying error from os/exec
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command %q exited with status %d", e.Command, e.Code)
+}
+
+// Unwrap returns the underlying os/exec error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // Exec runs the command with the given params, exiting the process with any
 // errors from the underlying command.  If the command is successful, the
 // process is *not* exited; control simply returns from Exec().
-func Exec(command string, params interface{}) {
-	options, err := Create(params)
+func Exec(command string, params interface{}, opts ...Option) {
+	Main(func() error {
+		return Execute(command, params, opts...)
+	})
+}
+
+// Execute runs the command with the given params, much like Exec(), but
+// returns any error instead of exiting the process.  If the command itself
+// fails, the error is an *ExitError carrying the command's exit status.
+func Execute(command string, params interface{}, opts ...Option) error {
+	cfg := newRunConfig(opts)
+	c, err := cfg.encoder.CreateCommand(command, params, cfg.metadata)
 	if err != nil {
-		log.Fatalf("error creating options: %+v", err)
+		return fmt.Errorf("error creating options: %w", err)
 	}
 
-	err = Run(command, options)
-	if err != nil {
-		log.Printf("command returned an error: %+v", err)
-		exit, ok := err.(*exec.ExitError)
-		if ok {
-			status, ok := exit.Sys().(syscall.WaitStatus)
-			if ok {
-				os.Exit(status.ExitStatus())
-			}
-		}
-		log.Fatalln("unable to determine failing exit status")
+	// copied first, so that appending can't write into the caller's slice
+	opts = append(append([]Option(nil), opts...), WithSecrets(c.Secrets...))
+	return Run(command, c.Options, opts...)
+}
+
+// Main calls fn, and translates any returned error into the process exit
+// status: an *ExitError exits with the same status as the failed command, and
+// any other error exits with 1.  If fn is successful, the process is *not*
+// exited; control simply returns from Main().  This allows a plugin's main()
+// to be written in terms of error-returning functions:
+//
+//	func main() {
+//		cmd.Main(func() error {
+//			return simple.Execute("helm", &Params{})
+//		})
+//	}
+func Main(fn func() error) {
+	err := fn()
+	if err == nil {
+		return
 	}
+
+	log.Printf("error: %v", err)
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		exit(exitErr.Code)
+		return
+	}
+	exit(1)
+}
+
+// Run mirrors the os/exec `Cmd.Run()` funciton.  If the command exits with a
+// non-zero status, the returned error is an *ExitError.
+func Run(command string, options []string, opts ...Option) (err error) {
+	return RunContext(context.Background(), command, options, opts...)
 }
 
-// Run mirrors the os/exec `Cmd.Run()` funciton.
-func Run(command string, options []string) (err error) {
-	cmd := exec.Command(command, options...)
+// RunContext is like Run(), but stops the command when ctx is done (or when
+// the WithTimeout() option expires).  The command is first asked to stop with
+// SIGTERM, and is killed if it is still running after the grace period.
+// Likewise, any SIGINT or SIGTERM sent to this process while the command is
+// running is forwarded to the command, so that it has the chance to clean up
+// (release locks, and so on) when Drone cancels the build.
+func RunContext(ctx context.Context, command string, options []string, opts ...Option) (err error) {
+	cfg := newRunConfig(opts)
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	c := Command{Name: command, Options: options, Secrets: cfg.secrets}
+
+	if cfg.script != "" {
+		err = c.WriteScript(cfg.script)
+		if err != nil {
+			return
+		}
+	}
+
+	if cfg.dryRun != nil {
+		err = writeDryRun(cfg.dryRun, c, cfg.env)
+		return
+	}
+
+	cmd := exec.CommandContext(ctx, command, options...)
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if len(cfg.env) > 0 {
+		cmd.Env = append(os.Environ(), cfg.env...)
+	}
+	cmd.Stdout = cfg.stdout
+	cmd.Stderr = cfg.stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = cfg.gracePeriod
 
 	// cmd.Run() doesn't need to quote the arguments because they are already
-	// separated into array elements... we would like to show the command-to-run
-	// to the user, but don't want to spend the effort to "minimally quote" the
-	// way that a hand-typed command-line would be.  Go's "%q" formatting of the
-	// array is good enough.
-	log.Printf("Running command: %q\n", cmd.Args)
-	err = cmd.Run()
+	// separated into array elements... but we show the command-to-run to the
+	// user minimally quoted, so that it can be copy-pasted into a terminal.  Any
+	// secret values are masked first, so that they never end up in the build
+	// log.
+	log.Printf("Running command: %s\n", c.Redacted())
+	err = cmd.Start()
+	if err != nil {
+		return
+	}
+
+	stop := forwardSignals(cmd.Process, cfg.gracePeriod)
+	err = cmd.Wait()
+	stop()
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		err = &ExitError{Command: command, Code: exitCode(exitErr), Err: exitErr}
+	}
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("command %q stopped: %w: %w", command, ctx.Err(), err)
+	}
+	return
+}
+
+// writeDryRun shows the variables passed to the command (see WithEnv()) and
+// the command-line, in a form that could be pasted into a shell.  A variable
+// whose name looks like a secret is masked, as are any secret values.  The
+// command-line is always the final line.
+func writeDryRun(w io.Writer, c Command, env []string) (err error) {
+	environ := append([]string{}, env...)
+	sort.Strings(environ)
+
+	_, err = fmt.Fprintln(w, "# dry run: the command was not executed")
+	if err != nil {
+		return
+	}
+
+	for _, envVar := range environ {
+		keyValue := strings.SplitN(envVar, "=", 2)
+		if len(keyValue) != 2 || !envNameRe.MatchString(keyValue[0]) {
+			continue
+		}
+		value := Redact([]string{keyValue[1]}, c.Secrets...)[0]
+		if isSecretEnvName(keyValue[0]) {
+			value = Mask
+		}
+		_, err = fmt.Fprintf(w, "export %s=%s\n", keyValue[0], quoteArg(value))
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = fmt.Fprintln(w, c.Redacted())
 	return
 }
+
+// forwardSignals passes SIGINT and SIGTERM on to the running process, killing
+// it if it hasn't exited after the grace period.  The returned function stops
+// the forwarding, and must be called once the process has exited.
+func forwardSignals(process *os.Process, gracePeriod time.Duration) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		var kill *time.Timer
+		defer func() {
+			if kill != nil {
+				kill.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case sig := <-signals:
+				log.Printf("forwarding %v to command", sig)
+				process.Signal(sig)
+				if kill == nil && gracePeriod > 0 {
+					kill = time.AfterFunc(gracePeriod, func() {
+						log.Printf("command did not exit after %v, killing", gracePeriod)
+						process.Kill()
+					})
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// exitCode determines the exit status for a failed command.  A command killed
+// by a signal is reported the way a shell would: 128 plus the signal number.
+func exitCode(exitErr *exec.ExitError) int {
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+		if status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return status.ExitStatus()
+	}
+
+	if code := exitErr.ExitCode(); code > 0 {
+		return code
+	}
+	return 1
+}
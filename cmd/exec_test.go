@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunExitError(t *testing.T) {
+	err := Run("sh", []string{"-c", "exit 3"})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *ExitError, got %v", err)
+	}
+	if exitErr.Code != 3 {
+		t.Errorf("expected exit status 3, got %d", exitErr.Code)
+	}
+}
+
+func TestRunSuccess(t *testing.T) {
+	err := Run("true", nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	start := time.Now()
+	err := Run("sleep", []string{"5"}, WithTimeout(100*time.Millisecond))
+	if err == nil {
+		t.Fatal("missing expected error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("command was not stopped promptly (%v)", elapsed)
+	}
+}
+
+func TestRunContextGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// the shell ignores SIGTERM, so it must be killed after the grace period
+	start := time.Now()
+	err := RunContext(ctx, "sh", []string{"-c", "trap '' TERM; while :; do sleep 0.1; done"}, WithGracePeriod(200*time.Millisecond))
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *ExitError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("command was not killed promptly (%v)", elapsed)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	t.Setenv("DRY_RUN_INHERITED", "not shown")
+
+	var b strings.Builder
+	env := WithEnv("DRY_RUN_TEST=it's here", "DRY_RUN_PASSWORD=hunter2")
+	err := Run("false", []string{"--flag", "two words", "--password", "hunter2"}, env, WithSecrets("hunter2"), DryRun(&b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	expected := "false --flag 'two words' --password '******'"
+	if actual := lines[len(lines)-1]; actual != expected {
+		t.Errorf("expected command %q, got %q", expected, actual)
+	}
+
+	for _, expected := range []string{
+		`export DRY_RUN_TEST='it'\''s here'`,
+		`export DRY_RUN_PASSWORD='******'`,
+	} {
+		if !strings.Contains(b.String(), expected+"\n") {
+			t.Errorf("expected output to contain %q", expected)
+		}
+	}
+	if strings.Contains(b.String(), "DRY_RUN_INHERITED") {
+		t.Errorf("expected the inherited environment to be left out, got %q", b.String())
+	}
+}
+
+func TestExecuteDryRunSecrets(t *testing.T) {
+	t.Setenv("PLUGIN_API_KEY", "hunter2")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "abc123")
+
+	params := struct {
+		APIKey string `cmd:",secret"`
+	}{"hunter2"}
+
+	var b strings.Builder
+	err := Execute("false", &params, DryRun(&b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, secret := range []string{"hunter2", "abc123"} {
+		if strings.Contains(b.String(), secret) {
+			t.Errorf("expected %q to be masked, got %q", secret, b.String())
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if expected, actual := "false --api-key '******'", lines[len(lines)-1]; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestExecuteOptionsUnchanged(t *testing.T) {
+	params := struct{ Debug bool }{true}
+
+	var b strings.Builder
+	opts := make([]Option, 1, 2)
+	opts[0] = DryRun(&b)
+	err := Execute("false", &params, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts[:2][1] != nil {
+		t.Error("expected the caller's options to be left alone")
+	}
+}
+
+func TestMainExitStatus(t *testing.T) {
+	examples := []struct {
+		name     string
+		err      error
+		expected int // -1 means "did not exit"
+	}{
+		{"success", nil, -1},
+		{"exit error", &ExitError{Command: "dummy", Code: 42}, 42},
+		{"other error", errors.New("bogus"), 1},
+	}
+
+	defer func() { exit = os.Exit }()
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			actual := -1
+			exit = func(code int) { actual = code }
+
+			Main(func() error { return local.err })
+
+			if actual != local.expected {
+				t.Errorf("expected exit status %d, got %d", local.expected, actual)
+			}
+		})
+	}
+}
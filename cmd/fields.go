@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+// ConflictError is returned when two fields would generate the same flag, or
+// when two fields have the same name at the same depth (so that neither one
+// hides the other).
+type ConflictError struct {
+	Struct string // name of the root struct
+	Flag   string // the flag both fields would generate (if any)
+	First  string // Go path of the first field, like "GlobalParams.Debug"
+	Second string // Go path of the second field
+}
+
+func (e *ConflictError) Error() string {
+	if e.Flag == "" {
+		return fmt.Sprintf("fields %s and %s in %s are ambiguous (same name at the same depth)", e.First, e.Second, e.Struct)
+	}
+	return fmt.Sprintf("fields %s and %s in %s both generate the %q flag", e.First, e.Second, e.Struct, e.Flag)
+}
+
+// field is a single command-line-generating field of a struct, which may be
+// nested inside inner/embedded structs.
+type field struct {
+	*fields.Field
+	info tagInfo
+}
+
+// plan is the cached, cmd-specific information about a struct type's fields,
+// built on top of the shared fields.Plan.
+type plan struct {
+	fields []*field
+	err    error
+}
+
+var (
+	plans sync.Map // map[planKey]*plan
+)
+
+// planKey identifies a plan: since flag names are derived by splitting field
+// names into words, the initialisms and naming in use matter as much as the
+// type.  (Any FlagNamer's naming follows from the type.)
+type planKey struct {
+	typ    reflect.Type
+	set    string // the names.Set's Key()
+	naming Naming // the Encoder's naming, which must be comparable
+}
+
+// valueKind returns the kind of value the field holds (ignoring pointers and
+// optional.Value wrappers), and for a slice, the kind of its elements.
+func (f *field) valueKind() (kind reflect.Kind, slice bool) {
+	typ := fields.TypeIndirect(f.StructField.Type)
+	if elem, ok := optional.ElemType(typ); ok {
+		typ = fields.TypeIndirect(elem)
+	}
+	if typ.Kind() == reflect.Slice {
+		return typ.Elem().Kind(), true
+	}
+	return typ.Kind(), false
+}
+
+// Validate checks that the params struct type (or pointer to one) can be used
+// to create a command-line: that the tags are valid, that a flag name can be
+// derived for each field, and that no two fields generate the same flag.
+// Create() performs the same checks, but Validate() allows problems to be
+// caught (in a test, for instance) before any values are available.
+func Validate(typ reflect.Type) (err error) {
+	return (&Encoder{}).Validate(typ)
+}
+
+// Validate is like the package-level Validate(), using the Encoder's naming.
+func (e *Encoder) Validate(typ reflect.Type) (err error) {
+	typ = fields.TypeIndirect(typ)
+	if typ.Kind() != reflect.Struct {
+		err = fmt.Errorf("expected struct type, got %s", typ)
+		return
+	}
+
+	_, err = e.structFields(typ)
+	return
+}
+
+// structFields returns the (cached) fields of the struct type, in field order.
+// Like Go's own rules for embedded fields, a field hides any field of the same
+// name that is more deeply nested.  Two fields of the same name at the same
+// depth, or two fields that would generate the same flag, are reported as a
+// *ConflictError.
+func (e *Encoder) structFields(typ reflect.Type) ([]*field, error) {
+	naming, err := e.naming()
+	if err != nil {
+		return nil, err
+	}
+	set := e.names()
+	key := planKey{fields.TypeIndirect(typ), set.Key(), naming}
+	if p, ok := plans.Load(key); ok {
+		return p.(*plan).fields, p.(*plan).err
+	}
+
+	p, _ := plans.LoadOrStore(key, compile(key, set))
+	return p.(*plan).fields, p.(*plan).err
+}
+
+func compile(key planKey, set *names.Set) (p *plan) {
+	typ, naming := key.typ, key.naming
+	p = &plan{}
+
+	fp, err := fields.ForNames(typ, set)
+	if err != nil {
+		var ambiguous *fields.AmbiguousError
+		if errors.As(err, &ambiguous) {
+			err = ambiguousConflict(ambiguous, typ, set, naming)
+		}
+		p.err = err
+		return
+	}
+
+	flags := make(map[string]*field)
+	for _, ff := range fp.Fields {
+		f := &field{Field: ff}
+		f.info, p.err = infoFromField(ff.StructField, set, namingFor(typ, ff, naming))
+		if p.err != nil {
+			return
+		}
+
+		if !f.info.omit && !f.info.positional && !f.info.extra {
+			for _, flag := range f.flags() {
+				if other, ok := flags[flag]; ok {
+					p.err = &ConflictError{typ.Name(), flag, other.Path, f.Path}
+					return
+				}
+				flags[flag] = f
+			}
+		}
+
+		p.fields = append(p.fields, f)
+	}
+
+	return
+}
+
+// ambiguousConflict reports two same-named fields as a conflict, including the
+// flag if they would both generate the same one.
+func ambiguousConflict(ambiguous *fields.AmbiguousError, root reflect.Type, set *names.Set, naming Naming) error {
+	conflict := &ConflictError{
+		Struct: ambiguous.Struct,
+		First:  ambiguous.First.Path,
+		Second: ambiguous.Second.Path,
+	}
+
+	first, err := infoFromField(ambiguous.First.StructField, set, namingFor(root, ambiguous.First, naming))
+	if err != nil {
+		return err
+	}
+	second, err := infoFromField(ambiguous.Second.StructField, set, namingFor(root, ambiguous.Second, naming))
+	if err != nil {
+		return err
+	}
+	if first.flag == second.flag {
+		conflict.Flag = first.flag
+	}
+
+	return conflict
+}
+
+// FlagInfo describes how a field appears on the command-line.
+type FlagInfo struct {
+	Path       string // Go path of the field, like "GlobalParams.Debug"
+	Flag       string // the flag, like "--debug" (empty for positional and extra fields)
+	Negated    string // the negated flag, for a bool field tagged with `no`
+	Positional bool   // the value is a positional argument
+	Extra      bool   // the value is passed through as extra arguments
+	Omit       bool   // the field is never passed to the command
+	Secret     bool   // the value is masked in the build log
+}
+
+// Describe returns how each of the fields of the params struct type (or
+// pointer to one) appears on the command-line, in field order.  Problems with
+// the type are reported the same way as Validate().
+func Describe(typ reflect.Type) ([]FlagInfo, error) {
+	return (&Encoder{}).Describe(typ)
+}
+
+// Describe is like the package-level Describe(), using the Encoder's naming.
+func (e *Encoder) Describe(typ reflect.Type) (infos []FlagInfo, err error) {
+	err = e.Validate(typ)
+	if err != nil {
+		return
+	}
+
+	fs, _ := e.structFields(fields.TypeIndirect(typ))
+	for _, f := range fs {
+		info := FlagInfo{
+			Path:       f.Path,
+			Positional: f.info.positional,
+			Extra:      f.info.extra,
+			Omit:       f.info.omit,
+			Secret:     f.info.secret,
+		}
+		if !info.Positional && !info.Extra {
+			info.Flag = f.info.flag
+			if f.info.boolNo {
+				info.Negated = f.info.negated
+			}
+		}
+		infos = append(infos, info)
+	}
+	return
+}
+
+// flags returns all of the flags the field might generate.
+func (f *field) flags() []string {
+	flags := []string{f.info.flag}
+	if f.info.boolNo && f.info.negated != "" {
+		flags = append(flags, f.info.negated)
+	}
+	return flags
+}
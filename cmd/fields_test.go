@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type FieldsGlobal struct {
+	Debug bool
+	Host  string
+}
+
+type FieldsTLS struct {
+	TLS     bool
+	TLSHost string
+}
+
+type FieldsGet struct {
+	FieldsGlobal
+	Revision int
+	FieldsTLS
+}
+
+type FieldsGetValues struct {
+	All bool
+	FieldsGet
+	Debug bool `cmd:"--verbose"` // shadows FieldsGlobal.Debug
+}
+
+func TestValidate(t *testing.T) {
+	examples := []struct {
+		name     string
+		params   interface{}
+		conflict *ConflictError
+	}{
+		{"embedded", FieldsGet{}, nil},
+		{"shadowed", &FieldsGetValues{}, nil},
+		{
+			"same name, same depth",
+			struct {
+				FieldsGlobal
+				Other struct{ Host string }
+			}{},
+			&ConflictError{Flag: "--host", First: "FieldsGlobal.Host", Second: "Other.Host"},
+		},
+		{
+			"same name, different flags",
+			struct {
+				FieldsGlobal
+				Other struct {
+					Host string `cmd:"--other-host"`
+				}
+			}{},
+			&ConflictError{First: "FieldsGlobal.Host", Second: "Other.Host"},
+		},
+		{
+			"same flag",
+			struct {
+				FieldsGlobal
+				Server string `cmd:"--host"`
+			}{},
+			&ConflictError{Flag: "--host", First: "FieldsGlobal.Host", Second: "Server"},
+		},
+		{
+			"negated flag",
+			struct {
+				Verify   bool `cmd:",no"`
+				NoVerify bool
+			}{},
+			&ConflictError{Flag: "--no-verify", First: "Verify", Second: "NoVerify"},
+		},
+		{
+			"positional",
+			struct {
+				Host    string
+				Release string `cmd:"--host,positional"`
+			}{},
+			nil,
+		},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			err := Validate(reflect.TypeOf(local.params))
+			if local.conflict == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("expected *ConflictError, got %v", err)
+			}
+			if conflict.Flag != local.conflict.Flag || conflict.First != local.conflict.First || conflict.Second != local.conflict.Second {
+				t.Errorf("expected %+v, got %+v", local.conflict, conflict)
+			}
+		})
+	}
+}
+
+func TestValidateNotStruct(t *testing.T) {
+	err := Validate(reflect.TypeOf(42))
+	if err == nil {
+		t.Error("missing expected error validating non-struct")
+	}
+}
+
+func TestCreateShadowed(t *testing.T) {
+	params := &FieldsGetValues{
+		All: true,
+		FieldsGet: FieldsGet{
+			FieldsGlobal: FieldsGlobal{Debug: true, Host: "example.com"},
+			FieldsTLS:    FieldsTLS{TLS: true, TLSHost: "tls.example.com"},
+		},
+		Debug: true,
+	}
+
+	actual, err := Create(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// FieldsGlobal.Debug is hidden by the outer Debug
+	expected := []string{"--all", "--host", "example.com", "--tls", "--tls-host", "tls.example.com", "--verbose"}
+	equalStrings(t, expected, actual)
+}
+
+func TestDescribe(t *testing.T) {
+	infos, err := Describe(reflect.TypeOf(&struct {
+		Wait     bool   `cmd:",no"`
+		Password string `cmd:",secret"`
+		Internal string `cmd:",omit"`
+		Chart    string `cmd:",positional"`
+	}{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []FlagInfo{
+		{Path: "Wait", Flag: "--wait", Negated: "--no-wait"},
+		{Path: "Password", Flag: "--password", Secret: true},
+		{Path: "Internal", Flag: "--internal", Omit: true},
+		{Path: "Chart", Positional: true},
+	}
+	if !reflect.DeepEqual(infos, expected) {
+		t.Errorf("expected %+v, got %+v", expected, infos)
+	}
+
+	_, err = Describe(reflect.TypeOf(42))
+	if err == nil {
+		t.Error("missing expected error describing non-struct")
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultGracePeriod is how long a command is given to exit after being
+	// asked to stop, before it is killed.
+	DefaultGracePeriod = 10 * time.Second
+)
+
+// Option configures how a command is run.
+type Option func(*runConfig)
+
+type runConfig struct {
+	timeout     time.Duration
+	gracePeriod time.Duration
+	stdout      io.Writer
+	stderr      io.Writer
+	dryRun      io.Writer
+	script      string
+	metadata    Metadata
+	encoder     *Encoder
+	secrets     []string
+	env         []string
+}
+
+func newRunConfig(opts []Option) *runConfig {
+	cfg := &runConfig{
+		gracePeriod: DefaultGracePeriod,
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		encoder:     &Encoder{},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithTimeout stops the command if it is still running after the given
+// duration.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *runConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithGracePeriod sets how long the command is given to exit after being
+// asked to stop (on a timeout, a cancelled context, or a forwarded signal)
+// before it is killed.  A zero grace period waits indefinitely.
+func WithGracePeriod(gracePeriod time.Duration) Option {
+	return func(cfg *runConfig) {
+		cfg.gracePeriod = gracePeriod
+	}
+}
+
+// Capture keeps a copy of the command's stdout and stderr in out, while still
+// writing them to the log as usual.
+func Capture(out *Output) Option {
+	return func(cfg *runConfig) {
+		cfg.stdout = io.MultiWriter(cfg.stdout, &out.Stdout)
+		cfg.stderr = io.MultiWriter(cfg.stderr, &out.Stderr)
+	}
+}
+
+// DryRun writes the command that would be run (and any variables from WithEnv()
+// that it would be given) to w, instead of running it.  Secret values are
+// masked.  The rest of the environment is never shown, since it holds the
+// runner's own credentials, as well as every setting.  This is
+// useful both for debugging a pipeline and for asserting the generated
+// command-line in tests.
+func DryRun(w io.Writer) Option {
+	return func(cfg *runConfig) {
+		cfg.dryRun = w
+	}
+}
+
+// Script writes a standalone shell script to path that runs the same command,
+// so that a failing step can be re-run locally.  See Command.WriteScript().
+func Script(path string) Option {
+	return func(cfg *runConfig) {
+		cfg.script = path
+	}
+}
+
+// WithMetadata tells Execute() which fields of the params were explicitly set
+// (see CreateWithMetadata()), so that their options are included even when
+// they hold a "zero value".
+func WithMetadata(md Metadata) Option {
+	return func(cfg *runConfig) {
+		cfg.metadata = md
+	}
+}
+
+// WithEnv adds variables (as "NAME=value") to the environment the command is
+// run with, on top of the plugin's own environment.
+func WithEnv(vars ...string) Option {
+	return func(cfg *runConfig) {
+		cfg.env = append(cfg.env, vars...)
+	}
+}
+
+// WithSecrets adds values that should never appear in logged output, like a
+// token the plugin fetched for itself.  The values of `secret` fields are
+// masked without this option.
+func WithSecrets(values ...string) Option {
+	return func(cfg *runConfig) {
+		cfg.secrets = append(cfg.secrets, values...)
+	}
+}
+
+// WithEncoder tells Execute() to create the command-line with the Encoder (and
+// its naming), rather than with the package-level Create().
+func WithEncoder(e *Encoder) Option {
+	return func(cfg *runConfig) {
+		cfg.encoder = e
+	}
+}
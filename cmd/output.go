@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output holds the output of a command run with the Capture() option.  The
+// output is still written to the log as usual; Output simply keeps a copy so
+// that the plugin can inspect it afterwards.
+type Output struct {
+	Stdout bytes.Buffer
+	Stderr bytes.Buffer
+}
+
+// JSON decodes the captured stdout as JSON into v, as with json.Unmarshal().
+// This is handy for tools that can report results as JSON, like
+// `helm status -o json`.
+func (o *Output) JSON(v interface{}) error {
+	return json.Unmarshal(o.Stdout.Bytes(), v)
+}
+
+// YAML decodes the captured stdout as YAML into v, as with yaml.Unmarshal().
+func (o *Output) YAML(v interface{}) error {
+	return yaml.Unmarshal(o.Stdout.Bytes(), v)
+}
+
+// Find looks for the first match of re in the captured stdout, and returns the
+// value of its named subexpression.  For example, to get the image digest from
+// `docker push`:
+//
+//	digest, ok := out.Find(regexp.MustCompile(`digest: (?P<digest>sha256:[[:xdigit:]]+)`), "digest")
+func (o *Output) Find(re *regexp.Regexp, name string) (value string, ok bool) {
+	index := re.SubexpIndex(name)
+	if index < 0 {
+		return
+	}
+
+	m := re.FindSubmatch(o.Stdout.Bytes())
+	if m == nil || m[index] == nil {
+		return
+	}
+
+	value = string(m[index])
+	ok = true
+	return
+}
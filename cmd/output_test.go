@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	out := &Output{}
+	err := Run("sh", []string{"-c", "echo stdout; echo stderr >&2"}, Capture(out))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if actual := out.Stdout.String(); actual != "stdout\n" {
+		t.Errorf("expected stdout to be %q, got %q", "stdout\n", actual)
+	}
+	if actual := out.Stderr.String(); actual != "stderr\n" {
+		t.Errorf("expected stderr to be %q, got %q", "stderr\n", actual)
+	}
+}
+
+func TestOutputJSON(t *testing.T) {
+	out := &Output{}
+	out.Stdout.WriteString(`{"name": "release", "info": {"status": "deployed"}}`)
+
+	var actual struct {
+		Name string
+		Info struct {
+			Status string
+		}
+	}
+	err := out.JSON(&actual)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.Name != "release" || actual.Info.Status != "deployed" {
+		t.Errorf("unexpected value: %+v", actual)
+	}
+}
+
+func TestOutputYAML(t *testing.T) {
+	out := &Output{}
+	out.Stdout.WriteString("name: release\ninfo:\n  status: deployed\n")
+
+	var actual struct {
+		Name string
+		Info struct {
+			Status string
+		}
+	}
+	err := out.YAML(&actual)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.Name != "release" || actual.Info.Status != "deployed" {
+		t.Errorf("unexpected value: %+v", actual)
+	}
+}
+
+func TestOutputFind(t *testing.T) {
+	out := &Output{}
+	out.Stdout.WriteString("latest: digest: sha256:0123abcd size: 1234\n")
+
+	re := regexp.MustCompile(`digest: (?P<digest>sha256:[[:xdigit:]]+)`)
+
+	examples := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"digest", "sha256:0123abcd", true},
+		{"bogus", "", false},
+	}
+
+	for _, ex := range examples {
+		actual, ok := out.Find(re, ex.name)
+		if ok != ex.ok || actual != ex.expected {
+			t.Errorf("for %q, expected %q (%v), got %q (%v)", ex.name, ex.expected, ex.ok, actual, ok)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/JaredReisinger/drone-plugin-helper/env"
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+// ParseError represents a problem with a specific command-line argument.
+type ParseError struct {
+	Arg     string // the argument that could not be parsed
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cannot parse argument %q: %s", e.Arg, e.Message)
+}
+
+// Parse is the inverse of Create(): it populates the struct pointed to by out
+// from the command-line options in argv, using the same `cmd` tag information
+// (flag names, `positional`, `no`, and so on).  Flags may be given as
+// "--flag value" or "--flag=value", and positional arguments are assigned in
+// field order; a "--" argument ends the flags.  A bool flag's value (as in
+// "--debug=on") accepts the same words as env.Parse().  Returns the first
+// unknown flag, missing value, or invalid value encountered (if any) as a
+// *ParseError.
+func Parse(argv []string, out interface{}) (err error) {
+	return (&Encoder{}).Parse(argv, out)
+}
+
+// Parse is like the package-level Parse(), using the Encoder's naming.
+func (e *Encoder) Parse(argv []string, out interface{}) (err error) {
+	val := reflect.ValueOf(out)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		err = fmt.Errorf("expected pointer to struct, got %s", val.Kind())
+		return
+	}
+
+	root := fields.Ensure(val)
+	if root.Kind() != reflect.Struct {
+		err = fmt.Errorf("expected pointer to struct, got pointer to %s", root.Kind())
+		return
+	}
+
+	flags := make(map[string]*field)
+	negated := make(map[string]*field)
+	var positionals []*field
+
+	fs, err := e.structFields(root.Type())
+	if err != nil {
+		return
+	}
+
+	for _, f := range fs {
+		switch {
+		// extra arguments are passed through as-is, and can't be parsed back
+		case f.info.omit || f.info.extra:
+			continue
+		case f.info.positional:
+			positionals = append(positionals, f)
+		default:
+			flags[f.info.flag] = f
+			if f.info.boolNo && f.info.negated != "" {
+				negated[f.info.negated] = f
+			}
+		}
+	}
+
+	onlyPositional := false
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+
+		if onlyPositional || arg == "-" || !strings.HasPrefix(arg, "-") {
+			if len(positionals) == 0 {
+				err = &ParseError{arg, "unexpected positional argument"}
+				return
+			}
+			err = e.setParsedField(root, positionals[0], arg, arg)
+			if err != nil {
+				return
+			}
+			// a slice consumes all of the remaining positional arguments
+			if _, slice := positionals[0].valueKind(); !slice {
+				positionals = positionals[1:]
+			}
+			continue
+		}
+
+		if arg == "--" {
+			onlyPositional = true
+			continue
+		}
+
+		flag, value, hasValue := strings.Cut(arg, "=")
+
+		if f, ok := negated[flag]; ok {
+			if hasValue {
+				err = &ParseError{arg, "negated flag does not take a value"}
+				return
+			}
+			err = e.setParsedField(root, f, arg, "false")
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		f, ok := flags[flag]
+		if !ok {
+			err = &ParseError{arg, "unknown flag"}
+			return
+		}
+
+		if !hasValue {
+			if kind, _ := f.valueKind(); kind == reflect.Bool {
+				value = "true"
+			} else {
+				if i+1 >= len(argv) {
+					err = &ParseError{arg, "missing value"}
+					return
+				}
+				i++
+				value = argv[i]
+			}
+		}
+
+		err = e.setParsedField(root, f, arg, value)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// setParsedField sets the field from the string value, creating any pointers
+// (including pointers to embedded structs) along the way.
+func (e *Encoder) setParsedField(root reflect.Value, f *field, arg string, value string) (err error) {
+	field, ok := f.Ensure(root)
+	if !ok || !field.CanSet() {
+		err = &ParseError{arg, fmt.Sprintf("cannot set field %q", f.Name)}
+		return
+	}
+
+	if o, ok := optional.From(field); ok {
+		field = fields.Ensure(o.Reflect())
+		o.MarkSet()
+	}
+
+	kind, slice := f.valueKind()
+	if slice {
+		elem := reflect.New(field.Type().Elem()).Elem()
+		err = e.setParsedValue(elem, kind, f.Name, arg, value)
+		if err != nil {
+			return
+		}
+		field.Set(reflect.Append(field, elem))
+		return
+	}
+
+	err = e.setParsedValue(field, kind, f.Name, arg, value)
+	return
+}
+
+// setParsedValue sets a single scalar value from the string value.
+func (e *Encoder) setParsedValue(field reflect.Value, kind reflect.Kind, name string, arg string, value string) (err error) {
+	switch kind {
+	case reflect.Bool:
+		b, err2 := env.ParseBool(value, e.TrueWords, e.FalseWords)
+		if err2 != nil {
+			err = &ParseError{arg, err2.Error()}
+			return
+		}
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err2 := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err2 != nil {
+			err = &ParseError{arg, fmt.Sprintf("invalid integer value %q", value)}
+			return
+		}
+		field.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err2 := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err2 != nil {
+			err = &ParseError{arg, fmt.Sprintf("invalid unsigned integer value %q", value)}
+			return
+		}
+		field.SetUint(n)
+
+	case reflect.String:
+		field.SetString(value)
+
+	default:
+		err = &ParseError{arg, fmt.Sprintf("unsupported parameter type for %q: %q", name, kind)}
+	}
+
+	return
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+type ParseEmbedded struct {
+	Debug bool
+	Host  string
+}
+
+type parseParams struct {
+	*ParseEmbedded
+	Verify   bool `cmd:",no"`
+	Timeout  int
+	Max      *uint8
+	Renamed  string `cmd:"--other"`
+	Omitted  string `cmd:",omit"`
+	Release  string `cmd:",positional"`
+	Revision string `cmd:",positional"`
+}
+
+func TestParse(t *testing.T) {
+	actual := &parseParams{Verify: true}
+	err := Parse([]string{
+		"--debug",
+		"--host=example.com",
+		"--no-verify",
+		"--timeout", "-5",
+		"--max", "0",
+		"--other", "value",
+		"my-release",
+		"--",
+		"--revision",
+	}, actual)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if actual.ParseEmbedded == nil || !actual.Debug || actual.Host != "example.com" {
+		t.Errorf("embedded fields not parsed: %+v", actual.ParseEmbedded)
+	}
+	if actual.Verify {
+		t.Error("expected verify to be false")
+	}
+	if actual.Timeout != -5 {
+		t.Errorf("expected timeout to be -5, got %d", actual.Timeout)
+	}
+	if actual.Max == nil || *actual.Max != 0 {
+		t.Errorf("expected max to be set to 0, got %v", actual.Max)
+	}
+	if actual.Renamed != "value" {
+		t.Errorf("expected renamed to be %q, got %q", "value", actual.Renamed)
+	}
+	if actual.Release != "my-release" || actual.Revision != "--revision" {
+		t.Errorf("unexpected positionals: %q, %q", actual.Release, actual.Revision)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	max := uint8(0)
+	expected := &parseParams{
+		ParseEmbedded: &ParseEmbedded{Debug: true, Host: "example.com"},
+		Timeout:       30,
+		Max:           &max,
+		Renamed:       "two words",
+		Release:       "my-release",
+	}
+
+	line, err := Create(expected)
+	if err != nil {
+		t.Fatalf("unexpected error creating: %v", err)
+	}
+
+	actual := &parseParams{}
+	err = Parse(line, actual)
+	if err != nil {
+		t.Fatalf("unexpected error parsing %q: %v", line, err)
+	}
+
+	roundTrip, err := Create(actual)
+	if err != nil {
+		t.Fatalf("unexpected error re-creating: %v", err)
+	}
+	equalStrings(t, line, roundTrip)
+}
+
+func TestParseErrors(t *testing.T) {
+	examples := []struct {
+		name string
+		argv []string
+		arg  string
+	}{
+		{"unknown flag", []string{"--bogus"}, "--bogus"},
+		{"missing value", []string{"--timeout"}, "--timeout"},
+		{"invalid value", []string{"--timeout", "abc"}, "--timeout"},
+		{"invalid bool", []string{"--verify=maybe"}, "--verify=maybe"},
+		{"omitted flag", []string{"--omitted", "x"}, "--omitted"},
+		{"negated with value", []string{"--no-verify=true"}, "--no-verify=true"},
+		{"extra positional", []string{"one", "two", "three"}, "three"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			err := Parse(local.argv, &parseParams{})
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected *ParseError, got %v", err)
+			}
+			if parseErr.Arg != local.arg {
+				t.Errorf("expected error for %q, got %q", local.arg, parseErr.Arg)
+			}
+		})
+	}
+}
+
+func TestParseBoolWords(t *testing.T) {
+	examples := []struct {
+		name     string
+		encoder  Encoder
+		arg      string
+		expected bool
+		ok       bool
+	}{
+		{"on", Encoder{}, "--verify=on", true, true},
+		{"no", Encoder{}, "--verify=NO", false, true},
+		{"short", Encoder{}, "--verify=t", false, false},
+		{"custom", Encoder{TrueWords: []string{"enabled"}, FalseWords: []string{"disabled"}}, "--verify=disabled", false, true},
+		{"custom default", Encoder{TrueWords: []string{"enabled"}, FalseWords: []string{"disabled"}}, "--verify=yes", false, false},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			actual := &parseParams{Verify: !local.expected}
+			err := local.encoder.Parse([]string{local.arg}, actual)
+			if !local.ok {
+				if err == nil {
+					t.Error("missing expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual.Verify != local.expected {
+				t.Errorf("expected %t, got %t", local.expected, actual.Verify)
+			}
+		})
+	}
+}
+
+func TestParseSlices(t *testing.T) {
+	actual := &struct {
+		Set       []string
+		Max       []int
+		ExtraArgs string
+		Plugins   []string `cmd:",positional"`
+	}{}
+
+	err := Parse([]string{"--set", "a=1", "one", "--set=b=2", "--max", "3", "two"}, actual)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	equalStrings(t, []string{"a=1", "b=2"}, actual.Set)
+	equalStrings(t, []string{"one", "two"}, actual.Plugins)
+	if len(actual.Max) != 1 || actual.Max[0] != 3 {
+		t.Errorf("expected max to be [3], got %v", actual.Max)
+	}
+
+	err = Parse([]string{"--extra-args", "x"}, actual)
+	if err == nil {
+		t.Error("missing expected error for extra arguments field")
+	}
+}
+
+func TestParseOptional(t *testing.T) {
+	actual := &struct {
+		Retries optional.Value[int]
+		Timeout optional.Value[int]
+		Debug   optional.Value[bool]
+		Set     optional.Value[[]string]
+	}{}
+
+	err := Parse([]string{"--retries", "0", "--debug", "--set", "a", "--set", "b"}, actual)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !actual.Retries.IsSet() || actual.Retries.Get() != 0 {
+		t.Errorf("expected retries to be set to 0, got %v", actual.Retries)
+	}
+	if actual.Timeout.IsSet() {
+		t.Errorf("expected timeout to be unset, got %v", actual.Timeout)
+	}
+	if !actual.Debug.Get() {
+		t.Error("expected debug to be true")
+	}
+	equalStrings(t, []string{"a", "b"}, actual.Set.Get())
+}
+
+func TestParseNoPtrError(t *testing.T) {
+	err := Parse(nil, parseParams{})
+	if err == nil {
+		t.Error("missing expected error parsing into non-pointer")
+	}
+}
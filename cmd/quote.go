@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// safeRe matches arguments that need no quoting at all in a POSIX shell.
+	safeRe = regexp.MustCompile(`^[[:alnum:]_@%+=:,./-]+$`)
+)
+
+// Quote renders args as a single line that a POSIX shell would split back into
+// the same arguments.  Arguments are only quoted when needed, so that the
+// common case looks just like a hand-typed command-line, and the result can be
+// pasted into a terminal to reproduce the command.
+func Quote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg quotes a single argument.  Single quotes prevent any expansion by
+// the shell; a single quote within the argument has to end the quoted string,
+// add an escaped quote, and start a new quoted string.
+func quoteArg(arg string) string {
+	if safeRe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
+
+// SplitWords splits s into words the way a POSIX shell would, honoring single
+// quotes, double quotes, and backslash escapes (but performing no expansion of
+// variables, globs, and so on).  It is the inverse of Quote(), and is used for
+// extra arguments given as a single string.
+func SplitWords(s string) (words []string, err error) {
+	var word strings.Builder
+	inWord := false
+	var quote rune // the open quote character, if any
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			// Within double quotes, a backslash only escapes a few characters.
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				word.WriteRune('\\')
+			}
+			if r != '\n' {
+				word.WriteRune(r)
+			}
+			escaped = false
+
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case r == '\\':
+			escaped = true
+			inWord = true
+
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if escaped {
+		err = fmt.Errorf("unterminated escape at end of %q", s)
+		return
+	}
+	if quote != 0 {
+		err = fmt.Errorf("unterminated %c quote in %q", quote, s)
+		return
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return
+}
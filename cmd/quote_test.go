@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestQuoteArg(t *testing.T) {
+	examples := []struct {
+		in       string
+		expected string
+	}{
+		{"simple", "simple"},
+		{"--flag=value", "--flag=value"},
+		{"./some/path.txt", "./some/path.txt"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a*b", "'a*b'"},
+		{`back\slash`, `'back\slash'`},
+	}
+
+	for _, ex := range examples {
+		actual := quoteArg(ex.in)
+		if actual != ex.expected {
+			t.Errorf("for %q, expected %s, got %s", ex.in, ex.expected, actual)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	actual := Quote([]string{"helm", "delete", "--purge", "my release"})
+	expected := "helm delete --purge 'my release'"
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	examples := []struct {
+		in       string
+		expected []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"one", []string{"one"}},
+		{"  one   two\tthree\n", []string{"one", "two", "three"}},
+		{`--set 'a=b c'`, []string{"--set", "a=b c"}},
+		{`--set "a=b c"`, []string{"--set", "a=b c"}},
+		{`it\'s`, []string{"it's"}},
+		{`'it'\''s'`, []string{"it's"}},
+		{`"say \"hi\""`, []string{`say "hi"`}},
+		{`"back\slash"`, []string{`back\slash`}},
+		{`''`, []string{""}},
+		{`a''b`, []string{"ab"}},
+		{"one\\\ntwo", []string{"onetwo"}},
+		{`'$HOME'`, []string{"$HOME"}},
+	}
+
+	for _, ex := range examples {
+		actual, err := SplitWords(ex.in)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", ex.in, err)
+			continue
+		}
+		equalStrings(t, ex.expected, actual)
+	}
+}
+
+func TestSplitWordsErrors(t *testing.T) {
+	for _, in := range []string{`'unterminated`, `"unterminated`, `trailing\`} {
+		_, err := SplitWords(in)
+		if err == nil {
+			t.Errorf("missing expected error for %q", in)
+		}
+	}
+}
+
+func TestSplitWordsQuoteRoundTrip(t *testing.T) {
+	args := []string{"helm", "--set", "a=b c", "it's", "", `"quoted"`, `$HOME`, "tab\there"}
+
+	actual, err := SplitWords(Quote(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equalStrings(t, args, actual)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// Mask is the text used in place of a secret value in logged output.
+	Mask = "******"
+)
+
+var (
+	// SecretEnvPatterns are the (path.Match-style) patterns for environment
+	// variable names whose values are considered secret.  Drone exposes
+	// secrets to the step as environment variables, and these cover the common
+	// naming conventions for them.  Any value from a matching variable is
+	// masked in logged output (see Redact()).
+	SecretEnvPatterns = []string{
+		"DRONE_SECRET_*",
+		"*_PASSWORD",
+		"*_SECRET",
+		"*_TOKEN",
+	}
+)
+
+// Redact returns a copy of args with the secret values masked, along with the
+// values of any environment variables matching SecretEnvPatterns.  Only whole
+// values are masked: an argument that is a secret, or the value of a
+// "--flag=value" argument that is one.  (Masking substrings would let a short
+// secret, like "1", garble unrelated arguments.)
+func Redact(args []string, secrets ...string) []string {
+	values := secretValues(os.Environ(), secrets)
+
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = redactArg(arg, values)
+	}
+	return redacted
+}
+
+// redactArg masks the argument if it is a secret value, or the value part of
+// a "--flag=value" argument if that is.
+func redactArg(arg string, values map[string]bool) string {
+	if values[arg] {
+		return Mask
+	}
+	if strings.HasPrefix(arg, "-") {
+		if flag, value, ok := strings.Cut(arg, "="); ok && values[value] {
+			return flag + "=" + Mask
+		}
+	}
+	return arg
+}
+
+// secretValues returns the given secrets, and the values of any environment
+// variables that look like secrets.
+func secretValues(environ []string, secrets []string) map[string]bool {
+	values := make(map[string]bool)
+	for _, value := range secrets {
+		if value != "" {
+			values[value] = true
+		}
+	}
+
+	for _, envVar := range environ {
+		name, value, ok := strings.Cut(envVar, "=")
+		if ok && value != "" && isSecretEnvName(name) {
+			values[value] = true
+		}
+	}
+	return values
+}
+
+func isSecretEnvName(name string) bool {
+	for _, pattern := range SecretEnvPatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestIsSecretEnvName(t *testing.T) {
+	examples := []struct {
+		in       string
+		expected bool
+	}{
+		{"DRONE_SECRET_DOCKER_PASSWORD", true},
+		{"PLUGIN_PASSWORD", true},
+		{"PLUGIN_API_TOKEN", true},
+		{"AWS_SECRET", true},
+		{"PLUGIN_USERNAME", false},
+		{"DRONE_BRANCH", false},
+	}
+
+	for _, ex := range examples {
+		actual := isSecretEnvName(ex.in)
+		if actual != ex.expected {
+			t.Errorf("for %q, expected %v, got %v", ex.in, ex.expected, actual)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Setenv("PLUGIN_TEST_TOKEN", "s3cr3t")
+
+	actual := Redact([]string{"--password", "hunter2", "--token=s3cr3t", "--user", "bob"}, "hunter2")
+	expected := []string{"--password", Mask, "--token=" + Mask, "--user", "bob"}
+	equalStrings(t, expected, actual)
+}
+
+func TestRedactWholeValues(t *testing.T) {
+	// a short secret doesn't garble the arguments that merely contain it
+	actual := Redact([]string{"--replicas", "10", "--tag=v1.1", "--retries=1", "1"}, "1")
+	expected := []string{"--replicas", "10", "--tag=v1.1", "--retries=" + Mask, Mask}
+	equalStrings(t, expected, actual)
+}
+
+func TestCreateSecret(t *testing.T) {
+	params := struct {
+		User     string
+		Password string `cmd:",secret"`
+	}{"bob", "correct-horse"}
+
+	c, err := CreateCommand("login", &params, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the real value goes on the command line...
+	equalStrings(t, []string{"--user", "bob", "--password", "correct-horse"}, c.Options)
+
+	// ... but is masked when logged
+	if expected, actual := "login --user bob --password '******'", c.Redacted(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	// ... and isn't remembered by anything else
+	if expected, actual := "login --user bob --password correct-horse", (Command{Name: "login", Options: c.Options}).Redacted(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func equalStrings(t *testing.T, expected []string, actual []string) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+		return
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected, actual)
+			return
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+const envTagName = "env"
+
+var (
+	// DefaultTrueWords are the (case-insensitive) words accepted as true for a
+	// bool setting, unless a Decoder or `env` tag says otherwise.
+	DefaultTrueWords = []string{"true", "on", "yes", "1"}
+
+	// DefaultFalseWords are the (case-insensitive) words accepted as false for
+	// a bool setting, unless a Decoder or `env` tag says otherwise.
+	DefaultFalseWords = []string{"false", "off", "no", "0"}
+
+	defaultVocabulary = vocabulary{DefaultTrueWords, DefaultFalseWords}
+)
+
+// Empty describes how an empty value (like `PLUGIN_DEBUG=`, which is what
+// Drone provides for a setting with an empty YAML value) is handled.
+type Empty int
+
+const (
+	// EmptyDefault is the zero Empty, which leaves the handling to the
+	// default: EmptyParse for bool fields (so an empty bool is an error), and
+	// EmptySkip for other fields.
+	EmptyDefault Empty = iota
+	// EmptyParse parses the empty string like any other value: a string is set
+	// to "", a list to an empty list, and anything else is an error.
+	EmptyParse
+	// EmptySkip ignores the value, as if the variable were not present at all.
+	EmptySkip
+	// EmptyZero sets the field to its "zero value" (false, 0, "", and so on).
+	EmptyZero
+	// EmptyTrue sets a bool field to true, as if the setting were a flag.  It
+	// is only valid for bool fields, so a Decoder's Empty (which is for the
+	// other fields) can't be EmptyTrue.
+	EmptyTrue
+	// EmptyError reports an empty value as an error.
+	EmptyError
+)
+
+var emptyNames = []string{"default", "parse", "skip", "zero", "true", "error"}
+
+func (e Empty) String() string {
+	if e < 0 || int(e) >= len(emptyNames) {
+		return fmt.Sprintf("Empty(%d)", int(e))
+	}
+	return emptyNames[e]
+}
+
+// parseEmpty parses the name of an `env` tag empty mode; "default" isn't one,
+// since the tag is only needed to change the default.
+func parseEmpty(name string) (e Empty, ok bool) {
+	for i, n := range emptyNames {
+		if n == name && Empty(i) != EmptyDefault {
+			return Empty(i), true
+		}
+	}
+	return
+}
+
+// Decoder parses environment variables into a struct, like Parse(), with
+// configurable handling of bool and empty values.  The zero Decoder behaves
+// exactly like Parse().  Individual fields can override the Decoder with an
+// `env` tag of comma-separated options:
+//
+//	true=enabled|y    the words accepted as true (for a bool field)
+//	false=disabled|n  the words accepted as false (for a bool field)
+//	empty=skip        how an empty value is handled: parse, skip, zero,
+//	                  true (bool fields only), or error
+//
+// A Decoder is safe for concurrent use, so long as it isn't modified.
+type Decoder struct {
+	TrueWords  []string // words accepted as true; nil means DefaultTrueWords
+	FalseWords []string // words accepted as false; nil means DefaultFalseWords
+	EmptyBool  Empty    // how an empty value is handled for bool fields
+	Empty      Empty    // how an empty value is handled for other fields; can't be EmptyTrue
+
+	// Names are the initialisms used to match variable names to fields; nil
+	// means names.Default().  Use the Decoder's Extract() so that the variable
+	// names are normalized with the same initialisms.
+	Names *names.Set
+}
+
+// check reports a Decoder whose settings don't make sense.
+func (d *Decoder) check() error {
+	for _, mode := range []Empty{d.EmptyBool, d.Empty} {
+		if mode < 0 || int(mode) >= len(emptyNames) {
+			return fmt.Errorf("unknown empty mode: %s", mode)
+		}
+	}
+	if d.Empty == EmptyTrue {
+		return fmt.Errorf("empty mode %q only applies to bool fields (see Decoder.EmptyBool)", d.Empty)
+	}
+	return nil
+}
+
+func (d *Decoder) names() *names.Set {
+	if d.Names == nil {
+		return names.Default()
+	}
+	return d.Names
+}
+
+// Extract is like the package-level Extract(), using the Decoder's
+// initialisms.
+func (d *Decoder) Extract(environ []string, prefix string) map[string]string {
+	return extract(environ, prefix, d.names())
+}
+
+// Parse is like the package-level Parse(), using the Decoder's settings.
+func (d *Decoder) Parse(vars map[string]string, out interface{}) (unused map[string]string, err error) {
+	unused, _, err = d.ParseWithMetadata(vars, out)
+	return
+}
+
+// ParseWithMetadata is like the package-level ParseWithMetadata(), using the
+// Decoder's settings.
+func (d *Decoder) ParseWithMetadata(vars map[string]string, out interface{}) (unused map[string]string, md *Metadata, err error) {
+	return d.parse(vars, nil, out)
+}
+
+// ParseEnviron is like the package-level ParseEnviron(), using the Decoder's
+// settings.
+func (d *Decoder) ParseEnviron(environ []string, prefix string, out interface{}) (unused map[string]string, md *Metadata, err error) {
+	vars, variables := extractVariables(environ, prefix, d.names())
+	return d.parse(vars, variables, out)
+}
+
+// parse sets the fields from vars.  The variables are the environment
+// variables the values came from, by key, if known.
+func (d *Decoder) parse(vars map[string]string, variables map[string]string, out interface{}) (unused map[string]string, md *Metadata, err error) {
+	unused = make(map[string]string)
+	md = newMetadata()
+	err = d.check()
+	if err != nil {
+		return
+	}
+	val := reflect.ValueOf(out)
+
+	// we expect a pointer to a structure...
+	if val.Kind() != reflect.Ptr {
+		err = &ParseFieldError{Struct: "(struct)", Field: "(root)", Message: "expected pointer"}
+		return
+	}
+
+	val = val.Elem()
+	if !val.CanSet() {
+		err = &ParseFieldError{Struct: "(struct)", Field: "(root)", Message: "cannot set values"}
+		return
+	}
+
+	plan, err := fields.ForNames(val.Type(), d.names())
+	if err != nil {
+		return
+	}
+
+	for k, v := range vars {
+		if _, ok := plan.Field(k); !ok {
+			unused[k] = v
+		}
+	}
+
+	for _, f := range plan.Fields {
+		v, ok := vars[f.Name]
+		if !ok {
+			continue
+		}
+		variable := variables[f.Name]
+
+		var opts *tagOptions
+		opts, err = optionsFor(f)
+		if err != nil {
+			err = fieldError(val.Type(), f, variable, err.Error())
+			return
+		}
+
+		words := d.vocabulary(opts)
+		if v == "" {
+			switch mode := d.empty(f, opts); mode {
+			case EmptySkip:
+				continue
+			case EmptyError:
+				err = fieldError(val.Type(), f, variable, "a value is required (the setting is empty)")
+				return
+			case EmptyZero, EmptyTrue:
+				err = setEmpty(val, f, mode == EmptyTrue)
+				if err != nil {
+					err = withField(err, val.Type(), f, variable)
+					return
+				}
+				md.add(f.Path, f.Name, variable)
+				continue
+			}
+		}
+
+		field, ok := f.Ensure(val)
+		if !ok {
+			err = fieldError(val.Type(), f, variable, "cannot set the value")
+			return
+		}
+
+		err = setFieldWith(v, field, f.StructField, words)
+		if err != nil {
+			err = withField(err, val.Type(), f, variable)
+			return
+		}
+
+		md.add(f.Path, f.Name, variable)
+	}
+
+	err = validate(out, md, d.names())
+	return
+}
+
+// CheckType is like the package-level CheckType(), using the Decoder's
+// initialisms.
+func (d *Decoder) CheckType(typ reflect.Type) (err error) {
+	err = d.check()
+	if err != nil {
+		return
+	}
+	typ = fields.TypeIndirect(typ)
+	if typ.Kind() != reflect.Struct {
+		err = &ParseFieldError{Struct: "(struct)", Field: "(root)", Message: fmt.Sprintf("expected struct type, got %s", typ)}
+		return
+	}
+
+	plan, err := fields.ForNames(typ, d.names())
+	if err != nil {
+		return
+	}
+
+	for _, f := range plan.Fields {
+		_, err = optionsFor(f)
+		if err != nil {
+			err = fieldError(typ, f, "", err.Error())
+			return
+		}
+	}
+
+	return validatorFor(typ, d.names()).err
+}
+
+// vocabulary returns the bool vocabulary for a field.
+func (d *Decoder) vocabulary(opts *tagOptions) (words vocabulary) {
+	words = defaultVocabulary
+	if d.TrueWords != nil {
+		words.trueWords = d.TrueWords
+	}
+	if d.FalseWords != nil {
+		words.falseWords = d.FalseWords
+	}
+	if opts.trueWords != nil {
+		words.trueWords = opts.trueWords
+	}
+	if opts.falseWords != nil {
+		words.falseWords = opts.falseWords
+	}
+	return
+}
+
+// empty returns how an empty value is handled for a field.
+func (d *Decoder) empty(f *fields.Field, opts *tagOptions) Empty {
+	if opts.hasEmpty {
+		return opts.empty
+	}
+	if isBool(f.StructField.Type) {
+		if d.EmptyBool == EmptyDefault {
+			return EmptyParse
+		}
+		return d.EmptyBool
+	}
+	if d.Empty == EmptyDefault {
+		// an empty setting is treated as no setting at all, as it always has
+		// been; EmptyParse (or `env:"empty=parse"`) still parses it
+		return EmptySkip
+	}
+	return d.Empty
+}
+
+// isBool reports whether the type is a bool, ignoring pointers and
+// optional.Value wrappers.
+func isBool(typ reflect.Type) bool {
+	typ = fields.TypeIndirect(typ)
+	if elem, ok := optional.ElemType(typ); ok {
+		typ = fields.TypeIndirect(elem)
+	}
+	return typ.Kind() == reflect.Bool
+}
+
+// setEmpty sets the field for an empty value: to its "zero value", or to true
+// for a bool field.  Any pointers along the way are created, and an
+// optional.Value is marked as set.
+func setEmpty(root reflect.Value, f *fields.Field, asTrue bool) error {
+	field, ok := f.Ensure(root)
+	if !ok || !field.CanSet() {
+		return &ParsingError{fmt.Sprintf("cannot set value in %q", f.Name)}
+	}
+	if o, ok := optional.From(field); ok {
+		field = fields.Ensure(o.Reflect())
+		o.MarkSet()
+	}
+	if asTrue {
+		field.SetBool(true)
+	} else {
+		field.Set(reflect.Zero(field.Type()))
+	}
+	return nil
+}
+
+// ParseBool parses a bool value with the same words as Parse(), ignoring case;
+// nil words mean DefaultTrueWords and DefaultFalseWords.  The error lists the
+// accepted words.
+func ParseBool(from string, trueWords, falseWords []string) (result bool, err error) {
+	words := defaultVocabulary
+	if trueWords != nil {
+		words.trueWords = trueWords
+	}
+	if falseWords != nil {
+		words.falseWords = falseWords
+	}
+
+	result, ok := words.parseBool(from)
+	if !ok {
+		err = errors.New(boolError(from, reflect.TypeOf(result), words).Message)
+	}
+	return
+}
+
+// vocabulary is the set of words accepted for bool values.
+type vocabulary struct {
+	trueWords  []string
+	falseWords []string
+}
+
+// parseBool matches from against the words, ignoring case.
+func (v vocabulary) parseBool(from string) (result bool, ok bool) {
+	for _, word := range v.trueWords {
+		if strings.EqualFold(from, word) {
+			return true, true
+		}
+	}
+	for _, word := range v.falseWords {
+		if strings.EqualFold(from, word) {
+			return false, true
+		}
+	}
+	return
+}
+
+// String lists all of the accepted words.
+func (v vocabulary) String() string {
+	all := append(append([]string{}, v.trueWords...), v.falseWords...)
+	return strings.Join(all, ", ")
+}
+
+// tagOptions are the parsed options from a field's `env` tag.
+type tagOptions struct {
+	trueWords  []string
+	falseWords []string
+	empty      Empty
+	hasEmpty   bool
+}
+
+type cachedOptions struct {
+	opts *tagOptions
+	err  error
+}
+
+var (
+	fieldOptions sync.Map // map[*fields.Field]*cachedOptions
+)
+
+// optionsFor returns the (cached) `env` tag options for a field.  Since the
+// field plans are themselves cached, each *fields.Field is parsed only once.
+func optionsFor(f *fields.Field) (*tagOptions, error) {
+	if c, ok := fieldOptions.Load(f); ok {
+		return c.(*cachedOptions).opts, c.(*cachedOptions).err
+	}
+
+	opts, err := parseTagOptions(f.StructField.Tag.Get(envTagName), isBool(f.StructField.Type))
+	c, _ := fieldOptions.LoadOrStore(f, &cachedOptions{opts, err})
+	return c.(*cachedOptions).opts, c.(*cachedOptions).err
+}
+
+func parseTagOptions(tag string, isBool bool) (opts *tagOptions, err error) {
+	opts = &tagOptions{}
+	if tag == "" {
+		return
+	}
+
+	for _, part := range strings.Split(tag, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "true", "false":
+			if !isBool {
+				err = fmt.Errorf("env tag option %q only applies to bool fields", name)
+				return
+			}
+			words := strings.Split(value, "|")
+			if value == "" {
+				err = fmt.Errorf("env tag option %q requires at least one word", name)
+				return
+			}
+			if name == "true" {
+				opts.trueWords = words
+			} else {
+				opts.falseWords = words
+			}
+
+		case "empty":
+			var ok bool
+			opts.empty, ok = parseEmpty(value)
+			if !ok {
+				err = fmt.Errorf("unknown env tag empty mode: %q (expected one of %s)", value, strings.Join(emptyNames[EmptyParse:], ", "))
+				return
+			}
+			if opts.empty == EmptyTrue && !isBool {
+				err = fmt.Errorf("env tag option %q only applies to bool fields", part)
+				return
+			}
+			opts.hasEmpty = true
+
+		default:
+			err = fmt.Errorf("unknown env tag option: %q", part)
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,273 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+func TestDecoderWords(t *testing.T) {
+	d := &Decoder{
+		TrueWords:  []string{"enabled", "y"},
+		FalseWords: []string{"disabled", "n"},
+	}
+
+	examples := []struct {
+		from     string
+		expected bool
+		err      bool
+	}{
+		{"enabled", true, false},
+		{"Y", true, false},
+		{"disabled", false, false},
+		{"n", false, false},
+		{"true", false, true},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.from, func(t *testing.T) {
+			dummy := struct{ Debug bool }{!local.expected}
+			_, err := d.Parse(map[string]string{"Debug": local.from}, &dummy)
+			if local.err {
+				if err == nil || !strings.Contains(err.Error(), "expected one of enabled, y, disabled, n") {
+					t.Errorf("expected error listing accepted words, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dummy.Debug != local.expected {
+				t.Errorf("expected %t, got %t", local.expected, dummy.Debug)
+			}
+		})
+	}
+}
+
+func TestDecoderTagWords(t *testing.T) {
+	dummy := struct {
+		Cache bool `env:"true=enabled,false=disabled"`
+		Debug bool
+	}{}
+
+	_, err := Parse(map[string]string{"Cache": "enabled", "Debug": "yes"}, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !dummy.Cache || !dummy.Debug {
+		t.Errorf("unexpected values: %+v", dummy)
+	}
+
+	_, err = Parse(map[string]string{"Cache": "yes"}, &dummy)
+	if err == nil || !strings.Contains(err.Error(), "expected one of enabled, disabled") {
+		t.Errorf("expected error listing accepted words, got %v", err)
+	}
+}
+
+func TestDecoderEmpty(t *testing.T) {
+	type params struct {
+		Debug   bool
+		Verbose optional.Value[bool]
+		Count   *int
+		Name    string
+	}
+
+	examples := []struct {
+		name     string
+		decoder  Decoder
+		expected string // "" means an error is expected
+		set      string
+	}{
+		{"default", Decoder{}, "", ""},
+		{"skip", Decoder{EmptyBool: EmptySkip, Empty: EmptySkip}, "false <unset> <nil> \"\"", ""},
+		{"zero", Decoder{EmptyBool: EmptyZero, Empty: EmptyZero}, "false false 0 \"\"", "Debug Verbose Count Name"},
+		{"true", Decoder{EmptyBool: EmptyTrue, Empty: EmptyZero}, "true true 0 \"\"", "Debug Verbose Count Name"},
+		{"error", Decoder{EmptyBool: EmptyError, Empty: EmptyError}, "", ""},
+	}
+
+	vars := map[string]string{"Debug": "", "Verbose": "", "Count": "", "Name": ""}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			dummy := params{}
+			_, md, err := local.decoder.ParseWithMetadata(vars, &dummy)
+			if local.expected == "" {
+				if err == nil {
+					t.Error("missing expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			count := "<nil>"
+			if dummy.Count != nil {
+				count = fmt.Sprint(*dummy.Count)
+			}
+			actual := fmt.Sprintf("%t %v %s %q", dummy.Debug, dummy.Verbose, count, dummy.Name)
+			if actual != local.expected {
+				t.Errorf("expected %q, got %q", local.expected, actual)
+			}
+			if set := strings.Join(md.Fields(), " "); set != local.set {
+				t.Errorf("expected %q, got %q", local.set, set)
+			}
+		})
+	}
+}
+
+func TestDecoderEmptyDefault(t *testing.T) {
+	dummy := struct {
+		Count *int
+		Name  string
+		Tags  []string
+		Raw   string `env:"empty=parse"`
+	}{Name: "default"}
+
+	_, md, err := ParseWithMetadata(map[string]string{"Count": "", "Name": "", "Tags": "", "Raw": ""}, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dummy.Count != nil || dummy.Name != "default" || dummy.Tags != nil {
+		t.Errorf("expected empty values to be skipped, got %+v", dummy)
+	}
+	if expected, actual := "Raw", strings.Join(md.Fields(), " "); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestDecoderEmptyParse(t *testing.T) {
+	d := Decoder{Empty: EmptyParse}
+
+	dummy := struct {
+		Count int
+		Name  string
+	}{Name: "default"}
+
+	_, md, err := d.ParseWithMetadata(map[string]string{"Name": ""}, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dummy.Name != "" || !md.IsSet("Name") {
+		t.Errorf("expected empty name to be parsed, got %q", dummy.Name)
+	}
+
+	_, err = d.Parse(map[string]string{"Count": ""}, &dummy)
+	var fieldErr *ParseTypeError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "Count" {
+		t.Errorf("expected *ParseTypeError for Count, got %v", err)
+	}
+}
+
+func TestDecoderEmptyInvalid(t *testing.T) {
+	examples := []struct {
+		name    string
+		decoder Decoder
+	}{
+		{"true", Decoder{Empty: EmptyTrue}},
+		{"unknown", Decoder{EmptyBool: Empty(42)}},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			dummy := struct{ Name string }{}
+			_, err := local.decoder.Parse(map[string]string{"Name": ""}, &dummy)
+			if err == nil {
+				t.Error("missing expected error")
+			}
+			err = local.decoder.CheckType(reflect.TypeOf(dummy))
+			if err == nil {
+				t.Error("missing expected error from CheckType")
+			}
+		})
+	}
+}
+
+func TestDecoderEmptyTag(t *testing.T) {
+	dummy := struct {
+		Debug bool   `env:"empty=true"`
+		Name  string `env:"empty=error"`
+	}{}
+
+	_, err := Parse(map[string]string{"Debug": ""}, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !dummy.Debug {
+		t.Error("expected empty debug to be true")
+	}
+
+	_, err = Parse(map[string]string{"Name": ""}, &dummy)
+	if err == nil {
+		t.Error("missing expected error for empty name")
+	}
+}
+
+func TestParseTagOptionsErrors(t *testing.T) {
+	examples := []struct {
+		tag    string
+		isBool bool
+	}{
+		{"bogus", true},
+		{"true=", true},
+		{"true=y", false},
+		{"empty=maybe", true},
+		{"empty=true", false},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.tag, func(t *testing.T) {
+			_, err := parseTagOptions(local.tag, local.isBool)
+			if err == nil {
+				t.Error("missing expected error")
+			}
+		})
+	}
+}
+
+type embeddedName struct{ Name string }
+type otherName struct{ Name string }
+
+func TestCheckType(t *testing.T) {
+	examples := []struct {
+		name  string
+		value interface{}
+		ok    bool
+	}{
+		{"valid", &struct {
+			Debug bool   `env:"empty=true"`
+			Level string `validate:"oneof=info|debug"`
+		}{}, true},
+		{"not a struct", new(int), false},
+		{"env tag", struct {
+			Level string `env:"empty=true"`
+		}{}, false},
+		{"validate tag", struct {
+			Level string `validate:"bogus"`
+		}{}, false},
+		{"ambiguous", struct {
+			embeddedName
+			otherName
+		}{}, false},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			err := CheckType(reflect.TypeOf(local.value))
+			if local.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !local.ok && err == nil {
+				t.Error("missing expected error")
+			}
+		})
+	}
+}
@@ -11,7 +11,20 @@ import (
 // a map of normalized keys (prefix stripped, and Go-variable-name-cased) to
 // values (unmodified).
 func Extract(environ []string, prefix string) (vars map[string]string) {
+	return extract(environ, prefix, names.Default())
+}
+
+func extract(environ []string, prefix string, set *names.Set) (vars map[string]string) {
+	vars, _ = extractVariables(environ, prefix, set)
+	return
+}
+
+// extractVariables is extract(), also returning the (unmodified) name of the
+// variable each key came from, like "TillerNamespace" =>
+// "PLUGIN_TILLER_NAMESPACE".
+func extractVariables(environ []string, prefix string, set *names.Set) (vars map[string]string, variables map[string]string) {
 	vars = make(map[string]string)
+	variables = make(map[string]string)
 	for _, envVar := range environ {
 		// We could split all environment variables on "=", but since we'll only
 		// be keeping the ones with the matching prefix, we do the filtering first.
@@ -19,8 +32,9 @@ func Extract(environ []string, prefix string) (vars map[string]string) {
 			// Split the key/value, and save the key *without* the prefix.  Also
 			// normalize the key.
 			keyValue := strings.SplitN(envVar, "=", 2)
-			key := normalize(strings.TrimPrefix(keyValue[0], prefix))
+			key := normalize(strings.TrimPrefix(keyValue[0], prefix), set)
 			vars[key] = keyValue[1]
+			variables[key] = keyValue[0]
 		}
 	}
 
@@ -31,8 +45,9 @@ func Extract(environ []string, prefix string) (vars map[string]string) {
 // title-case based on underscore boundaries. This allows case-sensitive
 // matching to typical Go element names ("SOME_VARIABLE" => "SomeVariable").
 // It also recognizes known initialisms, and makes them all upper-case
-// ("XML_CERT_ID" => "XMLCertID").
-func normalize(from string) (to string) {
+// ("XML_CERT_ID" => "XMLCertID"), including plural initialisms
+// ("IMAGE_IDS" => "ImageIDs").
+func normalize(from string, set *names.Set) (to string) {
 	var b strings.Builder
 	parts := strings.Split(from, "_")
 
@@ -40,11 +55,19 @@ func normalize(from string) (to string) {
 		// TODO: inspect the error from the Builder
 
 		// Ensure initialisms are written in all upper-case
-		if names.IsInitialism(p) {
+		if set.IsInitialism(p) {
 			b.WriteString(strings.ToUpper(p))
 			continue
 		}
 
+		// ... and plural initialisms with a lower-case "s", as Go style does
+		// ("IMAGE_IDS" => "ImageIDs")
+		if set.IsPluralInitialism(p) {
+			b.WriteString(strings.ToUpper(p[:len(p)-1]))
+			b.WriteString("s")
+			continue
+		}
+
 		// All other parts are written CamelCased
 		for i, r := range p {
 			if i == 0 {
@@ -2,6 +2,8 @@ package env
 
 import (
 	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/names"
 )
 
 func TestNormalize(t *testing.T) {
@@ -14,10 +16,13 @@ func TestNormalize(t *testing.T) {
 		{"oNe", "One"},
 		{"xml", "XML"},
 		{"xml_cert_id", "XMLCertID"},
+		{"IMAGE_IDS", "ImageIDs"},
+		{"allowed_ips", "AllowedIPs"},
+		{"HTTPS", "HTTPS"},
 	}
 
 	for _, ex := range examples {
-		actual := normalize(ex.in)
+		actual := normalize(ex.in, names.Default())
 		if actual != ex.expected {
 			t.Errorf("normalize expected %q, got %q", ex.expected, actual)
 		}
@@ -42,3 +47,38 @@ func TestExtract(t *testing.T) {
 		t.Error("did not get expected value for one")
 	}
 }
+
+func TestDecoderNames(t *testing.T) {
+	d := &Decoder{Names: names.Default().With("CA")}
+	vars := d.Extract([]string{"PLUGIN_TLS_CA_CERT=ca.pem"}, "PLUGIN_")
+
+	dummy := struct{ TLSCACert string }{}
+	_, err := d.Parse(vars, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dummy.TLSCACert != "ca.pem" {
+		t.Errorf("expected %q, got %q", "ca.pem", dummy.TLSCACert)
+	}
+
+	// the default initialisms don't include "CA"
+	if vars := Extract([]string{"PLUGIN_TLS_CA_CERT=ca.pem"}, "PLUGIN_"); vars["TLSCaCert"] != "ca.pem" {
+		t.Errorf("unexpected default normalization: %v", vars)
+	}
+}
+
+func TestParsePluralInitialism(t *testing.T) {
+	vars := Extract([]string{"PLUGIN_IMAGE_IDS=a,b"}, "PLUGIN_")
+
+	dummy := struct{ ImageIDs []string }{}
+	unused, err := Parse(vars, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unused) != 0 {
+		t.Errorf("unexpected unused variables: %v", unused)
+	}
+	if len(dummy.ImageIDs) != 2 || dummy.ImageIDs[1] != "b" {
+		t.Errorf("expected %q, got %q", []string{"a", "b"}, dummy.ImageIDs)
+	}
+}
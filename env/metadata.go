@@ -0,0 +1,66 @@
+package env
+
+// Metadata records which fields were set by ParseWithMetadata(), and from
+// which variable.  Fields are identified by their Go path, like
+// "GlobalParams.Debug", the same way as the `fields` package.  A nil *Metadata
+// reports that nothing was set.
+type Metadata struct {
+	paths     []string          // set fields, in field order
+	sources   map[string]string // Go path => key in the parsed map
+	variables map[string]string // Go path => environment variable, if known
+}
+
+func newMetadata() *Metadata {
+	return &Metadata{sources: make(map[string]string), variables: make(map[string]string)}
+}
+
+// add records the field at path as set from the key in the parsed map, and
+// the environment variable the key was extracted from, if known.
+func (m *Metadata) add(path string, key string, variable string) {
+	m.paths = append(m.paths, path)
+	m.sources[path] = key
+	if variable != "" {
+		m.variables[path] = variable
+	}
+}
+
+// IsSet reports whether the field at path was set from a variable, even if it
+// was set to its "zero value".
+func (m *Metadata) IsSet(path string) bool {
+	_, ok := m.Source(path)
+	return ok
+}
+
+// Source returns the name of the environment variable that set the field at
+// path, like "PLUGIN_TILLER_NAMESPACE" or "DRONE_TILLER_NAMESPACE", as it
+// appeared in the environment given to ParseEnviron().  ParseWithMetadata()
+// is only given a map of normalized keys, so then it is the key instead, like
+// "TillerNamespace".
+func (m *Metadata) Source(path string) (name string, ok bool) {
+	if m == nil {
+		return
+	}
+	name, ok = m.sources[path]
+	if variable := m.variables[path]; variable != "" {
+		name = variable
+	}
+	return
+}
+
+// variable returns the environment variable that set the field at path, if
+// it is known.
+func (m *Metadata) variable(path string) string {
+	if m == nil {
+		return ""
+	}
+	return m.variables[path]
+}
+
+// Fields returns the Go paths of all of the fields that were set, in field
+// order.
+func (m *Metadata) Fields() []string {
+	if m == nil {
+		return nil
+	}
+	return append([]string(nil), m.paths...)
+}
@@ -3,11 +3,14 @@
 package env
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
 )
 
 // Notes
@@ -18,7 +21,7 @@ import (
 // convenience of embedded structures (or pointers to them) for shared parameter
 // values.  We also want to allow pointers to values in order to unambiguously
 // determine whether a value was set or not; otherwise, zero-values are assumed
-// to be unset.
+// to be unset unless ParseWithMetadata() is used to track them.
 
 // Can't make a map literal a const!
 var (
@@ -40,14 +43,28 @@ var (
 	}
 )
 
-// ParseFieldError represents an error with a specific field
+// DefaultPrefix is the prefix Drone adds to the names of plugin settings when
+// exposing them as environment variables, for use with Extract() and
+// ParseEnviron().
+const DefaultPrefix = "PLUGIN_"
+
+// ParseFieldError represents an error with a specific field.  Along with the
+// Go path of the field, it carries the names the pipeline author knows it by:
+// the setting in `.drone.yml`, and the environment variable it came from.
 type ParseFieldError struct {
 	Struct  string // name of the struct containing the field
-	Field   string // name of the field holding the Go value
+	Field   string // Go path of the field holding the value, like "GlobalParams.Debug"
+	Var     string // environment variable, like "PLUGIN_TILLER_NAMESPACE", if known (see ParseEnviron())
+	Setting string // setting name, like "tiller_namespace"
 	Message string
 }
 
+// Error describes the problem in terms of the setting, if known, and
+// otherwise in terms of the Go struct field.
 func (e *ParseFieldError) Error() string {
+	if e.Setting != "" {
+		return fmt.Sprintf("setting `%s`: %s", e.Setting, e.Message)
+	}
 	return fmt.Sprintf("parse error with Go struct field %s.%s: %s", e.Struct, e.Field, e.Message)
 }
 
@@ -56,17 +73,11 @@ type ParseTypeError struct {
 	ParseFieldError
 	Value string       // the value that failed to parse
 	Type  reflect.Type // type of Go value that could not be assigned/converted to
-	// Struct string       // name of the struct containing the field
-	// Field  string       // name of the field holding the Go value
-}
-
-func (e *ParseTypeError) Error() string {
-	return fmt.Sprintf("cannot parse %q into Go struct field %s.%s of type %s", e.Value, e.Struct, e.Field, e.Type)
 }
 
 // ParsingError needs a better name, and is used when a parsing ot assignment
-// problem occurs.  It should eventually be like the error from encoding/json
-// and provide the name/type of the failing field.
+// problem occurs.  Parse() reports these as a *ParseFieldError for the field,
+// so they are only seen directly from lower-level helpers.
 type ParsingError struct {
 	Message string
 }
@@ -76,128 +87,124 @@ func (e *ParsingError) Error() string {
 	return fmt.Sprintf("ParsingError: %s", e.Message)
 }
 
-// Parse deserializes values from the environment map (as returned by
-// env.Extract()) into the given object, based on name and type. Returns any
-// unused keys/values and the first error encountered (if any).
-// (TODO: tag values for parsing hints and/or aliases?)
-func Parse(vars map[string]string, out interface{}) (unused map[string]string, err error) {
-	unused = make(map[string]string)
-	val := reflect.ValueOf(out)
-
-	// we expect a pointer to a structure...
-	if val.Kind() != reflect.Ptr {
-		err = &ParseFieldError{"(struct)", "(root)", "expected pointer"}
-		return
+// SettingName returns the user-facing (snake_case) name of the setting for a
+// field, as it would be written in `.drone.yml`: "tiller_namespace" for the
+// PLUGIN_TILLER_NAMESPACE variable.
+func SettingName(f *fields.Field) string {
+	if len(f.Words) == 0 {
+		return strings.ToLower(f.Name)
 	}
+	return strings.ToLower(strings.Join(f.Words, "_"))
+}
 
-	val = val.Elem()
-	if !val.CanSet() {
-		err = &ParseFieldError{"(struct)", "(root)", "cannot set values"}
-		return
+// fieldError returns a *ParseFieldError for the field with the given message.
+// The variable is the environment variable the value came from, if known.
+func fieldError(typ reflect.Type, f *fields.Field, variable string, message string) *ParseFieldError {
+	setting := SettingName(f)
+	return &ParseFieldError{
+		Struct:  typ.Name(),
+		Field:   f.Path,
+		Var:     variable,
+		Setting: setting,
+		Message: message,
 	}
+}
 
-	// structType := val.Type()
-	// fields := val.NumField()
-
-	for k, v := range vars {
-		var found bool
-		found, err = parseValue(k, v, val)
-		if err != nil {
-			return
-		}
-
-		if !found {
-			unused[k] = v
-		}
+// withField adds the field information to an error from setField().
+func withField(err error, typ reflect.Type, f *fields.Field, variable string) error {
+	switch e := err.(type) {
+	case *ParseTypeError:
+		e.ParseFieldError = *fieldError(typ, f, variable, e.Message)
+		return e
+	case *ParsingError:
+		return fieldError(typ, f, variable, e.Message)
 	}
-
-	return
+	return err
 }
 
-// func dbgField(structVal reflect.Value, i int, field reflect.Value) {
-// 	sf := structVal.Type().Field(i)
-// 	typeDesc := make([]string, 0)
-// 	typ := field.Type()
-// 	for ; typ.Kind() == reflect.Ptr; typ = typ.Elem() {
-// 		typeDesc = append(typeDesc, "ptr-to")
-// 	}
-// 	typeDesc = append(typeDesc, typ.String())
-//
-// 	log.Printf("field [%d] %s %s (%s):", i, sf.Name, field.Type(), strings.Join(typeDesc, " "))
-// }
-
-// parseValue finds the field that matches key and attempts to set the value.
-// Recurses through inner/embedded structs transparently.
-func parseValue(key string, value string, into reflect.Value) (found bool, err error) {
-	// log.Printf("looking for %q in %s...", key, into.Type().String())
-	if into.Kind() != reflect.Struct {
-		err = &ParsingError{fmt.Sprintf("expected struct, got %s", into.Kind())}
-		return
+// boolError returns a *ParseTypeError (without any field information) for a
+// value that isn't one of the accepted words.
+func boolError(from string, typ reflect.Type, words vocabulary) *ParseTypeError {
+	return &ParseTypeError{
+		ParseFieldError: ParseFieldError{Message: fmt.Sprintf("%q is not a valid boolean (expected one of %s)", from, words)},
+		Value:           from,
+		Type:            typ,
 	}
+}
 
-	if !into.CanSet() {
-		err = &ParsingError{"cannot set values in 'into'"}
-		return
+// typeError returns a *ParseTypeError (without any field information) for a
+// value that cannot be converted to the type.
+func typeError(from string, typ reflect.Type, err error) *ParseTypeError {
+	var message string
+	var numErr *strconv.NumError
+	rangeErr := errors.As(err, &numErr) && errors.Is(numErr.Err, strconv.ErrRange)
+
+	switch kind := typ.Kind(); {
+	case rangeErr && kind >= reflect.Int && kind <= reflect.Int64:
+		bits := typ.Bits()
+		message = fmt.Sprintf("%q is out of range (%d to %d)", from, int64(-1)<<(bits-1), int64(1)<<(bits-1)-1)
+	case rangeErr && kind >= reflect.Uint && kind <= reflect.Uint64:
+		message = fmt.Sprintf("%q is out of range (0 to %d)", from, uint64(1)<<(typ.Bits()-1)*2-1)
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		message = fmt.Sprintf("%q is not a valid integer", from)
+	case kind >= reflect.Uint && kind <= reflect.Uint64:
+		message = fmt.Sprintf("%q is not a valid non-negative integer", from)
+	default:
+		message = fmt.Sprintf("%q is not a valid %s", from, typ)
 	}
 
-	structType := into.Type()
-	fields := into.NumField()
-
-	for fi := 0; fi < fields; fi++ {
-		field := into.Field(fi)
-		// dbgField(into, fi, field)
-
-		kind := typeIndirect(field.Type()).Kind()
-
-		if kind == reflect.Struct {
-			// recurse!
-			found, err = parseValue(key, value, ensure(field))
-			if err != nil || found {
-				return
-			}
-			continue
-		}
-
-		sf := structType.Field(fi)
-
-		// TODO: make case-(in)sensitve an option?
-		// found = strings.EqualFold(key, sf.Name)
-		if key == sf.Name {
-			found = true
-			err = setField(value, ensure(field), sf)
-			return
-		}
+	return &ParseTypeError{
+		ParseFieldError: ParseFieldError{Message: message},
+		Value:           from,
+		Type:            typ,
 	}
+}
 
+// Parse deserializes values from the environment map (as returned by
+// env.Extract()) into the given object, based on name and type. Returns any
+// unused keys/values and the first error encountered (if any).  Fields are
+// set in field order, so the same input always produces the same result (and
+// the same error).
+// (TODO: tag values for parsing hints and/or aliases?)
+func Parse(vars map[string]string, out interface{}) (unused map[string]string, err error) {
+	unused, _, err = ParseWithMetadata(vars, out)
 	return
 }
 
-// ensure takes a cue from encoding/json's decode.go helper 'indirect' that
-// does something similar... given a value/field (which may be a pointer),
-// creates the underlying data field when needed, and returns the actual
-// settable value
-func ensure(field reflect.Value) reflect.Value {
-	// This could be a 'for' to handle arbitrarily-deep pointer chains.
-	for field.Kind() == reflect.Ptr {
-		if field.IsNil() {
-			// log.Printf("ensuring %q for %q...", field.Type().Elem(), field.Type())
-			field.Set(reflect.New(field.Type().Elem()))
-		}
-		field = field.Elem()
-	}
+// ParseWithMetadata is like Parse(), but also returns a *Metadata recording
+// exactly which fields were set, and from which variable.  This allows a
+// value that was explicitly set to its "zero value" to be told apart from one
+// that was never set, without resorting to pointer fields.  Once all of the
+// values are set, they are checked against any `validate` tags (see
+// Validate()).
+func ParseWithMetadata(vars map[string]string, out interface{}) (unused map[string]string, md *Metadata, err error) {
+	return (&Decoder{}).ParseWithMetadata(vars, out)
+}
 
-	// log.Printf("returning field...")
-	return field
+// ParseEnviron extracts the variables with the given prefix from environ (as
+// Extract() does) and parses them into out, like ParseWithMetadata().  Since
+// the variables' own names are known, any *ParseFieldError names the variable
+// (in Var), and so does the Metadata (see Metadata.Source()).
+func ParseEnviron(environ []string, prefix string, out interface{}) (unused map[string]string, md *Metadata, err error) {
+	return (&Decoder{}).ParseEnviron(environ, prefix, out)
 }
 
-func typeIndirect(typ reflect.Type) reflect.Type {
-	for typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
-	}
-	return typ
+// CheckType checks that the struct type (or pointer to one) can be parsed into,
+// without needing any values: that no two fields have the same name at the
+// same depth, and that the `env` and `validate` tags are valid.  Parse()
+// performs the same checks, but CheckType() allows problems to be caught (at
+// startup, or in a test) before any environment is available.
+func CheckType(typ reflect.Type) error {
+	return (&Decoder{}).CheckType(typ)
 }
 
+// setField sets the field from the string value, using the default bool
+// vocabulary.
 func setField(from string, field reflect.Value, sf reflect.StructField) (err error) {
+	return setFieldWith(from, field, sf, defaultVocabulary)
+}
+
+func setFieldWith(from string, field reflect.Value, sf reflect.StructField, words vocabulary) (err error) {
 	// log.Printf("attempting to set field %q (%s, %v) from %q...", sf.Name, sf.Type, sf.Type.Kind(), from)
 	if !field.CanSet() {
 		err = &ParsingError{fmt.Sprintf("cannot set value in %q", sf.Name)}
@@ -209,12 +216,13 @@ func setField(from string, field reflect.Value, sf reflect.StructField) (err err
 	switch kind {
 
 	case reflect.Ptr:
-		log.Fatalf("should never see a pointer (for field %s %s)", sf.Name, field.Type())
+		// ensure() should have already dereferenced any pointers
+		err = &ParsingError{fmt.Sprintf("unexpected pointer for field %q (%s)", sf.Name, field.Type())}
 
 	case reflect.Bool:
-		b, ok := parseBool(from)
+		b, ok := words.parseBool(from)
 		if !ok {
-			err = &ParsingError{fmt.Sprintf("cannot parse %q as bool", from)}
+			err = boolError(from, field.Type(), words)
 			return
 		}
 		field.SetBool(b)
@@ -222,7 +230,7 @@ func setField(from string, field reflect.Value, sf reflect.StructField) (err err
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		n, err2 := strconv.ParseInt(from, 10, kindBits[kind])
 		if err2 != nil {
-			err = err2
+			err = typeError(from, field.Type(), err2)
 			return
 		}
 		field.SetInt(n)
@@ -230,22 +238,38 @@ func setField(from string, field reflect.Value, sf reflect.StructField) (err err
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		n, err2 := strconv.ParseUint(from, 10, kindBits[kind])
 		if err2 != nil {
-			err = err2
+			err = typeError(from, field.Type(), err2)
 			return
 		}
 		field.SetUint(n)
 
+	case reflect.Slice:
+		// Drone passes list settings as a single comma-separated value.
+		err = setSlice(from, field, sf, words)
+
 	// Float?  Complex?
 	// Array, Chan, Func, Interface,
 	// Map <<==
 	// Ptr ?
-	// Slice <<==
 
 	case reflect.String:
 		// direct assignment!
 		field.SetString(from)
 
-		// Struct?
+	case reflect.Struct:
+		// The only supported struct is an optional.Value, which is set (and
+		// marked as set) like the value it holds.
+		o, ok := optional.From(field)
+		if !ok {
+			err = &ParsingError{fmt.Sprintf("env parsing does not support parsing into %q (%q)", kind, sf.Name)}
+			return
+		}
+		err = setFieldWith(from, fields.Ensure(o.Reflect()), sf, words)
+		if err != nil {
+			return
+		}
+		o.MarkSet()
+
 	default:
 		err = &ParsingError{fmt.Sprintf("env parsing does not support parsing into %q (%q)", kind, sf.Name)}
 	}
@@ -253,24 +277,27 @@ func setField(from string, field reflect.Value, sf reflect.StructField) (err err
 	return
 }
 
-// TODO... make true/false string values data-driven
-// Should this fail with an error, or simply a !ok?
-func parseBool(from string) (result bool, ok bool) {
-	// We're using a case-insensitive(-ish) map, by using lower-case and forcing
-	// `from` to lower-case when checking.  Note that this is only safe because
-	// all of our strings are ASCII... there are unicode case-folding issues
-	// outside of this range.
-	known := map[string]bool{
-		"true":  true,
-		"on":    true,
-		"yes":   true,
-		"1":     true,
-		"false": false,
-		"off":   false,
-		"no":    false,
-		"0":     false,
+// setSlice sets a slice field from a comma-separated list of values, each of
+// which is parsed according to the slice's element type.
+func setSlice(from string, field reflect.Value, sf reflect.StructField, words vocabulary) (err error) {
+	elemType := field.Type().Elem()
+	if elemType.Kind() == reflect.Slice {
+		err = &ParsingError{fmt.Sprintf("env parsing does not support nested slices (%q)", sf.Name)}
+		return
+	}
+
+	slice := reflect.MakeSlice(field.Type(), 0, 0)
+	if from != "" {
+		for _, part := range strings.Split(from, ",") {
+			elem := reflect.New(elemType).Elem()
+			err = setFieldWith(part, elem, sf, words)
+			if err != nil {
+				return
+			}
+			slice = reflect.Append(slice, elem)
+		}
 	}
 
-	result, ok = known[strings.ToLower(from)]
+	field.Set(slice)
 	return
 }
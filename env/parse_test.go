@@ -3,7 +3,10 @@ package env
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
 )
 
 func TestSetFieldString(t *testing.T) {
@@ -111,7 +114,7 @@ func TestSetFieldInt(t *testing.T) {
 				if err != nil {
 					t.Errorf("unexpected error setting %v to %q", local.typ, local.from)
 				} else if dummy.Int() != local.expected {
-					t.Errorf("unexpected int value from %q: got %d, expected %d", local.from, dummy, local.expected)
+					t.Errorf("unexpected int value from %q: got %d, expected %d", local.from, dummy.Int(), local.expected)
 				}
 
 			} else {
@@ -153,7 +156,7 @@ func TestSetFieldUint(t *testing.T) {
 				if err != nil {
 					t.Errorf("unexpected error setting %v to %q", local.typ, local.from)
 				} else if dummy.Uint() != local.expected {
-					t.Errorf("unexpected int value from %q: got %d, expected %d", local.from, dummy, local.expected)
+					t.Errorf("unexpected int value from %q: got %d, expected %d", local.from, dummy.Uint(), local.expected)
 				}
 			} else {
 				if err == nil {
@@ -164,6 +167,43 @@ func TestSetFieldUint(t *testing.T) {
 	}
 }
 
+func TestSetFieldSlice(t *testing.T) {
+	examples := []struct {
+		from     string
+		typ      reflect.Type
+		valid    bool
+		expected string
+	}{
+		{"a,b,c", reflect.TypeOf([]string{}), true, "[a b c]"},
+		{"a", reflect.TypeOf([]string{}), true, "[a]"},
+		{"", reflect.TypeOf([]string{}), true, "[]"},
+		{"1,2,3", reflect.TypeOf([]int8{}), true, "[1 2 3]"},
+		{"1,x,3", reflect.TypeOf([]int8{}), false, ""},
+		{"yes,no", reflect.TypeOf([]bool{}), true, "[true false]"},
+		{"a,b", reflect.TypeOf([][]string{}), false, ""},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(fmt.Sprintf("%v %s", local.typ, local.from), func(t *testing.T) {
+			dummy := reflect.New(local.typ).Elem()
+			err := setField(
+				local.from,
+				dummy,
+				reflect.StructField{Name: "Dummy"})
+			if local.valid {
+				if err != nil {
+					t.Errorf("unexpected error setting %v to %q: %v", local.typ, local.from, err)
+				} else if actual := fmt.Sprintf("%v", dummy); actual != local.expected {
+					t.Errorf("unexpected slice value from %q: got %s, expected %s", local.from, actual, local.expected)
+				}
+			} else if err == nil {
+				t.Errorf("missing expected error setting %v to %q", local.typ, local.from)
+			}
+		})
+	}
+}
+
 func TestSetFieldUnsettableError(t *testing.T) {
 	dummy := ""
 	err := setField(
@@ -191,6 +231,18 @@ func TestSetFieldUnsupportedTypeError(t *testing.T) {
 	}
 }
 
+func TestSetFieldPointerError(t *testing.T) {
+	var dummy *string
+	err := setField(
+		"dummy",
+		reflect.ValueOf(&dummy).Elem(),
+		reflect.StructField{Name: "Dummy", Type: reflect.TypeOf(dummy)})
+
+	if err == nil {
+		t.Error("missing expected error setting pointer")
+	}
+}
+
 func TestParse(t *testing.T) {
 	dummy := struct {
 		Int    int
@@ -273,3 +325,193 @@ func TestParseInvalidValueError(t *testing.T) {
 		t.Error("missing expected error parsing values")
 	}
 }
+
+func TestParseFieldOrder(t *testing.T) {
+	dummy := struct {
+		First  int
+		Second int
+	}{}
+
+	values := map[string]string{
+		"First":  "one",
+		"Second": "two",
+	}
+
+	// the first field's error is always the one returned
+	for i := 0; i < 10; i++ {
+		_, err := Parse(values, &dummy)
+		if err == nil || !strings.Contains(err.Error(), `"one"`) {
+			t.Fatalf("expected error for %q, got %v", "one", err)
+		}
+	}
+}
+
+func TestParseWithMetadata(t *testing.T) {
+	type Inner struct {
+		Debug bool
+	}
+	dummy := struct {
+		*Inner
+		Retries int
+		Name    string
+	}{}
+
+	values := map[string]string{
+		"Debug":   "false",
+		"Retries": "0",
+		"Extra":   "wow",
+	}
+
+	unused, md, err := ParseWithMetadata(values, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"Inner.Debug", "Retries"}
+	if actual := md.Fields(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if !md.IsSet("Retries") {
+		t.Error("expected Retries to be set")
+	}
+	if md.IsSet("Name") {
+		t.Error("expected Name not to be set")
+	}
+	if source, _ := md.Source("Inner.Debug"); source != "Debug" {
+		t.Errorf("expected %q, got %q", "Debug", source)
+	}
+	if len(unused) != 1 || unused["Extra"] != "wow" {
+		t.Errorf("unexpected unused values: %v", unused)
+	}
+}
+
+func TestParseEnvironSource(t *testing.T) {
+	examples := []struct {
+		environ  []string
+		prefix   string
+		expected string
+	}{
+		{[]string{"PLUGIN_TILLER_NAMESPACE=kube-system"}, "PLUGIN_", "PLUGIN_TILLER_NAMESPACE"},
+		{[]string{"DRONE_TILLER_NAMESPACE=kube-system"}, "DRONE_", "DRONE_TILLER_NAMESPACE"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.expected, func(t *testing.T) {
+			dummy := struct{ TillerNamespace string }{}
+			_, md, err := ParseEnviron(local.environ, local.prefix, &dummy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if source, _ := md.Source("TillerNamespace"); source != local.expected {
+				t.Errorf("expected %q, got %q", local.expected, source)
+			}
+		})
+	}
+}
+
+func TestParseOptional(t *testing.T) {
+	dummy := struct {
+		Int    optional.Value[int]
+		Uint8  optional.Value[uint8]
+		Bool   optional.Value[bool]
+		String optional.Value[string] `env:"empty=parse"`
+		List   optional.Value[[]string]
+		Ptr    optional.Value[*int]
+		Unset  optional.Value[int]
+	}{}
+
+	values := map[string]string{
+		"Int":    "0",
+		"Uint8":  "8",
+		"Bool":   "false",
+		"String": "",
+		"List":   "a,b",
+		"Ptr":    "3",
+	}
+
+	_, err := Parse(values, &dummy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := fmt.Sprintf("%v %v %v %q %v %v %v", dummy.Int, dummy.Uint8, dummy.Bool, dummy.String.Get(), dummy.List, *dummy.Ptr.Get(), dummy.Unset)
+	expected := `0 8 false "" [a b] 3 <unset>`
+	if actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if !dummy.String.IsSet() {
+		t.Error("expected empty String to be set")
+	}
+}
+
+func TestParseErrorMessages(t *testing.T) {
+	type Inner struct {
+		TillerConnectionTimeout int
+	}
+	dummy := struct {
+		Inner
+		Int8  int8
+		Uint8 uint8
+		Debug bool
+	}{}
+
+	examples := []struct {
+		environ  []string
+		expected string
+		envVar   string
+		path     string
+	}{
+		{[]string{"PLUGIN_TILLER_CONNECTION_TIMEOUT=abc"}, "setting `tiller_connection_timeout`: \"abc\" is not a valid integer", "PLUGIN_TILLER_CONNECTION_TIMEOUT", "Inner.TillerConnectionTimeout"},
+		{[]string{"PLUGIN_INT8=128"}, "setting `int8`: \"128\" is out of range (-128 to 127)", "PLUGIN_INT8", "Int8"},
+		{[]string{"PLUGIN_UINT8=-1"}, "setting `uint8`: \"-1\" is not a valid non-negative integer", "PLUGIN_UINT8", "Uint8"},
+		{[]string{"PLUGIN_UINT8=256"}, "setting `uint8`: \"256\" is out of range (0 to 255)", "PLUGIN_UINT8", "Uint8"},
+		{[]string{"PLUGIN_DEBUG=maybe"}, "setting `debug`: \"maybe\" is not a valid boolean (expected one of true, on, yes, 1, false, off, no, 0)", "PLUGIN_DEBUG", "Debug"},
+		{[]string{"DRONE_DEBUG=maybe"}, "setting `debug`: \"maybe\" is not a valid boolean (expected one of true, on, yes, 1, false, off, no, 0)", "DRONE_DEBUG", "Debug"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.expected, func(t *testing.T) {
+			prefix := local.environ[0][:strings.Index(local.environ[0], "_")+1]
+			_, _, err := ParseEnviron(local.environ, prefix, &dummy)
+			if err == nil {
+				t.Fatal("missing expected error")
+			}
+			if err.Error() != local.expected {
+				t.Errorf("expected %q, got %q", local.expected, err.Error())
+			}
+
+			typeErr, ok := err.(*ParseTypeError)
+			if !ok {
+				t.Fatalf("expected *ParseTypeError, got %T", err)
+			}
+			if typeErr.Var != local.envVar {
+				t.Errorf("expected %q, got %q", local.envVar, typeErr.Var)
+			}
+			if typeErr.Field != local.path {
+				t.Errorf("expected %q, got %q", local.path, typeErr.Field)
+			}
+		})
+	}
+}
+
+func TestParseEnvironValidationVar(t *testing.T) {
+	dummy := struct {
+		Output string `validate:"oneof=table|json"`
+	}{}
+
+	unused, _, err := ParseEnviron([]string{"PLUGIN_OUTPUT=xml", "PLUGIN_OTHER=1", "HOME=/root"}, DefaultPrefix, &dummy)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if expected := "PLUGIN_OUTPUT"; validationErr.Var != expected {
+		t.Errorf("expected %q, got %q", expected, validationErr.Var)
+	}
+	if _, ok := unused["Other"]; !ok || len(unused) != 1 {
+		t.Errorf("unexpected unused values: %v", unused)
+	}
+}
@@ -0,0 +1,386 @@
+package env
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+const validateTagName = "validate"
+
+// Rule is a single validation rule from a `validate` tag, like "min=1" or
+// "required".  Arg is empty for rules that don't take an argument.
+type Rule struct {
+	Name string
+	Arg  string
+}
+
+func (r Rule) String() string {
+	if r.Arg == "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%s=%s", r.Name, r.Arg)
+}
+
+// Rules is the list of validation rules for a field, in tag order.
+type Rules []Rule
+
+// Get returns the named rule, if present.
+func (rs Rules) Get(name string) (rule Rule, ok bool) {
+	for _, rule = range rs {
+		if rule.Name == name {
+			ok = true
+			return
+		}
+	}
+	rule = Rule{}
+	return
+}
+
+// ParseRules parses the value of a `validate` tag.  Rules are comma-separated:
+//
+//	required          a value must be given
+//	oneof=a|b|c       the value must be one of the listed values
+//	min=N, max=N      numeric bounds for numbers, length bounds for strings
+//	                  and lists
+//	pattern=RE        the value must match the regular expression; because
+//	                  the expression may itself contain commas, this must be
+//	                  the last rule in the tag
+//	file, dir         the value must be the path of an existing file/directory
+//	url               the value must be an absolute URL
+//	exclusive=GROUP   at most one field in the group may be given
+//	together=GROUP    either all or none of the fields in the group are given
+//
+// For lists, oneof, pattern, file, dir and url apply to each element.
+// ParseRules is exported so that tooling (like documentation generators) can
+// describe the same rules that are enforced.
+func ParseRules(tag string) (rules Rules, err error) {
+	rest := tag
+	for rest != "" {
+		var part string
+		part, rest, _ = strings.Cut(rest, ",")
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		if name == "pattern" {
+			// the pattern consumes the remainder of the tag
+			if rest != "" {
+				arg = fmt.Sprintf("%s,%s", arg, rest)
+				rest = ""
+			}
+		}
+
+		rule := Rule{name, arg}
+		err = checkRule(rule)
+		if err != nil {
+			return
+		}
+		rules = append(rules, rule)
+	}
+	return
+}
+
+func checkRule(rule Rule) (err error) {
+	needsArg := true
+	switch rule.Name {
+	case "required", "file", "dir", "url":
+		needsArg = false
+	case "oneof", "exclusive", "together":
+	case "min", "max":
+		if _, err2 := strconv.ParseFloat(rule.Arg, 64); err2 != nil {
+			err = fmt.Errorf("invalid %s rule: %q is not a number", rule.Name, rule.Arg)
+			return
+		}
+	case "pattern":
+		if _, err2 := regexp.Compile(rule.Arg); err2 != nil {
+			err = fmt.Errorf("invalid pattern rule: %w", err2)
+			return
+		}
+	default:
+		err = fmt.Errorf("unknown validate rule: %q", rule.Name)
+		return
+	}
+
+	if needsArg && rule.Arg == "" {
+		err = fmt.Errorf("%s rule requires a value", rule.Name)
+	} else if !needsArg && rule.Arg != "" {
+		err = fmt.Errorf("%s rule does not take a value", rule.Name)
+	}
+	return
+}
+
+// ValidationError reports a setting that failed a validation rule.
+type ValidationError struct {
+	ParseFieldError
+	Rule Rule // the rule that failed
+}
+
+// validator is the cached validation information for a struct type.
+type validator struct {
+	fields   []*validatedField
+	patterns map[string]*regexp.Regexp
+	err      error
+}
+
+type validatedField struct {
+	*fields.Field
+	rules Rules
+}
+
+var (
+	validators sync.Map // map[validatorKey]*validator
+)
+
+type validatorKey struct {
+	typ reflect.Type
+	set string // the names.Set's Key()
+}
+
+// Validate checks the values in the struct pointed to by out against the
+// rules in the fields' `validate` tags, returning the first failure as a
+// *ValidationError.  A field has a value if md reports it as set, or if it
+// holds anything other than its "zero value" (md may be nil).
+// ParseWithMetadata() calls Validate() automatically after parsing.
+func Validate(out interface{}, md *Metadata) (err error) {
+	return validate(out, md, names.Default())
+}
+
+// validate is Validate(), using the given initialisms for setting names.
+func validate(out interface{}, md *Metadata, set *names.Set) (err error) {
+	defer func() {
+		// name the variable, if the field was set from one
+		if e, ok := err.(*ValidationError); ok {
+			e.Var = md.variable(e.Field)
+		}
+	}()
+
+	val := fields.Indirect(reflect.ValueOf(out))
+	if val.Kind() != reflect.Struct {
+		err = &ParseFieldError{Struct: "(struct)", Field: "(root)", Message: "expected pointer to struct"}
+		return
+	}
+
+	v := validatorFor(val.Type(), set)
+	if v.err != nil {
+		err = v.err
+		return
+	}
+
+	groups := make(map[string][]*validatedField)
+	given := make(map[*validatedField]bool)
+
+	for _, f := range v.fields {
+		fieldVal, ok := f.Value(val)
+		if ok {
+			fieldVal, ok = unwrap(fieldVal)
+		}
+		given[f] = md.IsSet(f.Path) || (ok && !fieldVal.IsZero())
+
+		for _, rule := range f.rules {
+			switch rule.Name {
+			case "required":
+				if !given[f] {
+					err = validationError(val, f, rule, "a value is required")
+					return
+				}
+			case "exclusive", "together":
+				key := fmt.Sprintf("%s=%s", rule.Name, rule.Arg)
+				groups[key] = append(groups[key], f)
+			default:
+				if given[f] && ok {
+					err = v.check(val, f, rule, fieldVal)
+					if err != nil {
+						return
+					}
+				}
+			}
+		}
+	}
+
+	for _, f := range v.fields {
+		for _, rule := range f.rules {
+			key := fmt.Sprintf("%s=%s", rule.Name, rule.Arg)
+			group, ok := groups[key]
+			if !ok {
+				continue
+			}
+			delete(groups, key)
+			err = checkGroup(val, rule, group, given)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func validatorFor(typ reflect.Type, set *names.Set) *validator {
+	key := validatorKey{typ, set.Key()}
+	if v, ok := validators.Load(key); ok {
+		return v.(*validator)
+	}
+
+	v, _ := validators.LoadOrStore(key, compileValidator(typ, set))
+	return v.(*validator)
+}
+
+func compileValidator(typ reflect.Type, set *names.Set) (v *validator) {
+	v = &validator{patterns: make(map[string]*regexp.Regexp)}
+
+	plan, err := fields.ForNames(typ, set)
+	if err != nil {
+		v.err = err
+		return
+	}
+
+	for _, f := range plan.Fields {
+		tag, ok := f.StructField.Tag.Lookup(validateTagName)
+		if !ok {
+			continue
+		}
+
+		rules, err := ParseRules(tag)
+		if err != nil {
+			v.err = fieldError(typ, f, "", fmt.Sprintf("invalid validate tag: %s", err))
+			return
+		}
+
+		if rule, ok := rules.Get("pattern"); ok {
+			// already known to compile
+			v.patterns[rule.Arg] = regexp.MustCompile(rule.Arg)
+		}
+
+		v.fields = append(v.fields, &validatedField{f, rules})
+	}
+
+	return
+}
+
+// unwrap follows pointers and optional.Value wrappers to the actual value;
+// returns !ok if there's no value (a nil pointer or an unset optional.Value).
+func unwrap(val reflect.Value) (reflect.Value, bool) {
+	val = fields.Indirect(val)
+	if !val.IsValid() {
+		return val, false
+	}
+	if o, ok := optional.From(val); ok {
+		if !o.IsSet() {
+			return reflect.Value{}, false
+		}
+		return unwrap(o.Reflect())
+	}
+	return val, true
+}
+
+func validationError(root reflect.Value, f *validatedField, rule Rule, message string) error {
+	return &ValidationError{
+		ParseFieldError: *fieldError(root.Type(), f.Field, "", message),
+		Rule:            rule,
+	}
+}
+
+// check applies a single value rule to the field's value.
+func (v *validator) check(root reflect.Value, f *validatedField, rule Rule, val reflect.Value) (err error) {
+	switch rule.Name {
+	case "min", "max":
+		var n float64
+		var what string
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, what = float64(val.Int()), "value"
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, what = float64(val.Uint()), "value"
+		case reflect.Float32, reflect.Float64:
+			n, what = val.Float(), "value"
+		case reflect.String, reflect.Slice:
+			n, what = float64(val.Len()), "length"
+		default:
+			return validationError(root, f, rule, fmt.Sprintf("%s rule does not apply to %s", rule.Name, val.Kind()))
+		}
+
+		limit, _ := strconv.ParseFloat(rule.Arg, 64)
+		if rule.Name == "min" && n < limit {
+			return validationError(root, f, rule, fmt.Sprintf("%s must be at least %s", what, rule.Arg))
+		}
+		if rule.Name == "max" && n > limit {
+			return validationError(root, f, rule, fmt.Sprintf("%s must be at most %s", what, rule.Arg))
+		}
+		return
+	}
+
+	// the remaining rules apply to each element of a list
+	if val.Kind() == reflect.Slice {
+		for i := 0; i < val.Len(); i++ {
+			err = v.check(root, f, rule, val.Index(i))
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	s := fmt.Sprint(val.Interface())
+	switch rule.Name {
+	case "oneof":
+		options := strings.Split(rule.Arg, "|")
+		for _, option := range options {
+			if s == option {
+				return
+			}
+		}
+		err = validationError(root, f, rule, fmt.Sprintf("%q must be one of %s", s, strings.Join(options, ", ")))
+
+	case "pattern":
+		if !v.patterns[rule.Arg].MatchString(s) {
+			err = validationError(root, f, rule, fmt.Sprintf("%q does not match the pattern %q", s, rule.Arg))
+		}
+
+	case "file", "dir":
+		info, err2 := os.Stat(s)
+		switch {
+		case err2 != nil:
+			err = validationError(root, f, rule, fmt.Sprintf("%q does not exist", s))
+		case rule.Name == "file" && info.IsDir():
+			err = validationError(root, f, rule, fmt.Sprintf("%q is a directory, not a file", s))
+		case rule.Name == "dir" && !info.IsDir():
+			err = validationError(root, f, rule, fmt.Sprintf("%q is not a directory", s))
+		}
+
+	case "url":
+		u, err2 := url.Parse(s)
+		if err2 != nil || u.Scheme == "" || u.Host == "" {
+			err = validationError(root, f, rule, fmt.Sprintf("%q is not an absolute URL", s))
+		}
+	}
+
+	return
+}
+
+// checkGroup checks an `exclusive` or `together` group of fields.
+func checkGroup(root reflect.Value, rule Rule, group []*validatedField, given map[*validatedField]bool) error {
+	var set, unset []*validatedField
+	for _, f := range group {
+		if given[f] {
+			set = append(set, f)
+		} else {
+			unset = append(unset, f)
+		}
+	}
+
+	switch {
+	case rule.Name == "exclusive" && len(set) > 1:
+		return validationError(root, set[1], rule, fmt.Sprintf("cannot be used with `%s`", SettingName(set[0].Field)))
+	case rule.Name == "together" && len(set) > 0 && len(unset) > 0:
+		return validationError(root, unset[0], rule, fmt.Sprintf("is required when `%s` is given", SettingName(set[0].Field)))
+	}
+	return nil
+}
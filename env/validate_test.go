@@ -0,0 +1,167 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+func TestParseRules(t *testing.T) {
+	examples := []struct {
+		tag      string
+		expected Rules
+		err      bool
+	}{
+		{"required", Rules{{"required", ""}}, false},
+		{"oneof=table|json|yaml,min=1", Rules{{"oneof", "table|json|yaml"}, {"min", "1"}}, false},
+		{"required,pattern=^[a-z]{1,63}$", Rules{{"required", ""}, {"pattern", "^[a-z]{1,63}$"}}, false},
+		{"exclusive=auth,url", Rules{{"exclusive", "auth"}, {"url", ""}}, false},
+		{"bogus", nil, true},
+		{"min=abc", nil, true},
+		{"oneof", nil, true},
+		{"file=yes", nil, true},
+		{"pattern=(", nil, true},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.tag, func(t *testing.T) {
+			actual, err := ParseRules(local.tag)
+			if local.err {
+				if err == nil {
+					t.Errorf("missing expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, local.expected) {
+				t.Errorf("expected %v, got %v", local.expected, actual)
+			}
+		})
+	}
+}
+
+type validateParams struct {
+	Output     string              `validate:"oneof=table|json|yaml"`
+	Timeout    int                 `validate:"min=1,max=3600"`
+	Namespace  string              `validate:"pattern=^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$"`
+	Kubeconfig string              `validate:"file"`
+	ChartDir   string              `validate:"dir"`
+	Repo       string              `validate:"url"`
+	Values     []string            `validate:"max=2,oneof=a|b"`
+	Token      string              `validate:"exclusive=auth"`
+	Password   string              `validate:"exclusive=auth"`
+	CertFile   string              `validate:"together=tls"`
+	KeyFile    string              `validate:"together=tls"`
+	Retries    optional.Value[int] `validate:"min=1"`
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config")
+	err := os.WriteFile(file, []byte{}, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	examples := []struct {
+		name    string
+		vars    map[string]string
+		setting string // expected failing setting, or "" for success
+		rule    string
+	}{
+		{"empty", map[string]string{}, "", ""},
+		{"valid", map[string]string{
+			"Output":     "json",
+			"Timeout":    "30",
+			"Namespace":  "kube-system",
+			"Kubeconfig": file,
+			"ChartDir":   dir,
+			"Repo":       "https://charts.example.com/stable",
+			"Values":     "a,b",
+			"Token":      "t",
+			"CertFile":   file,
+			"KeyFile":    file,
+		}, "", ""},
+		{"oneof", map[string]string{"Output": "xml"}, "output", "oneof"},
+		{"min", map[string]string{"Timeout": "0"}, "timeout", "min"},
+		{"max", map[string]string{"Timeout": "3601"}, "timeout", "max"},
+		{"pattern", map[string]string{"Namespace": "Kube_System"}, "namespace", "pattern"},
+		{"file missing", map[string]string{"Kubeconfig": filepath.Join(dir, "nope")}, "kubeconfig", "file"},
+		{"file is dir", map[string]string{"Kubeconfig": dir}, "kubeconfig", "file"},
+		{"dir", map[string]string{"ChartDir": file}, "chart_dir", "dir"},
+		{"url", map[string]string{"Repo": "charts/stable"}, "repo", "url"},
+		{"list length", map[string]string{"Values": "a,b,a"}, "values", "max"},
+		{"list element", map[string]string{"Values": "a,c"}, "values", "oneof"},
+		{"exclusive", map[string]string{"Token": "t", "Password": "p"}, "password", "exclusive"},
+		{"together", map[string]string{"KeyFile": file}, "cert_file", "together"},
+		{"optional", map[string]string{"Retries": "0"}, "retries", "min"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			_, err := Parse(local.vars, &validateParams{})
+			if local.setting == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if validationErr.Setting != local.setting {
+				t.Errorf("expected %q, got %q", local.setting, validationErr.Setting)
+			}
+			if validationErr.Rule.Name != local.rule {
+				t.Errorf("expected %q, got %q", local.rule, validationErr.Rule.Name)
+			}
+		})
+	}
+}
+
+func TestValidateRequired(t *testing.T) {
+	params := &struct {
+		Name  string `validate:"required"`
+		Count int    `validate:"required"`
+	}{}
+
+	_, err := Parse(map[string]string{"Name": "x"}, params)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Setting != "count" {
+		t.Fatalf("expected required error for count, got %v", err)
+	}
+
+	// explicitly set to the zero value still counts
+	_, err = Parse(map[string]string{"Name": "x", "Count": "0"}, params)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// without metadata, only non-zero values count
+	err = Validate(params, nil)
+	if err == nil {
+		t.Error("missing expected error")
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	params := &struct {
+		Name string `validate:"oneof"`
+	}{}
+
+	err := Validate(params, nil)
+	var fieldErr *ParseFieldError
+	if !errors.As(err, &fieldErr) {
+		t.Errorf("expected *ParseFieldError, got %v", err)
+	}
+}
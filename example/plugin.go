@@ -8,13 +8,13 @@ import (
 // GlobalParams are the options available for any/all helm commands
 type GlobalParams struct {
 	simple.Command
-	Debug                   bool
-	Home                    string
-	Host                    string
-	KubeContext             string
-	Kubeconfig              string
-	TillerConnectionTimeout int
-	TillerNamespace         string
+	Debug                   bool   `doc:"enable verbose output"`
+	Home                    string `doc:"location of your Helm config"`
+	Host                    string `doc:"address of Tiller"`
+	KubeContext             string `doc:"name of the kubeconfig context to use"`
+	Kubeconfig              string `doc:"absolute path to the kubeconfig file to use" validate:"file"`
+	TillerConnectionTimeout int    `doc:"the duration (in seconds) Helm will wait to establish a connection to tiller" validate:"min=0"`
+	TillerNamespace         string `doc:"namespace of Tiller"`
 
 	// lifted from individual commands
 	Help bool
@@ -26,7 +26,7 @@ type ChartParams struct {
 	CertFile string
 	KeyFile  string
 	Keyring  string
-	Password string
+	Password string `cmd:",secret"`
 	Repo     string
 	Username string
 	Verify   bool
@@ -58,7 +58,7 @@ type DeleteParams struct {
 	DryRun      bool
 	NoHooks     bool
 	Purge       bool
-	Timeout     int
+	Timeout     int `validate:"min=0"`
 	TLSParams
 	ReleaseName string `cmd:",positional"`
 }
@@ -242,7 +242,7 @@ type RepoAddParams struct {
 	CertFile string
 	KeyFile  string
 	NoUpdate bool
-	Password string
+	Password string `cmd:",secret"`
 	Username string
 	Name     string `cmd:",positional"`
 	URL      string `cmd:",positional"`
@@ -380,49 +380,51 @@ type VersionParams struct {
 }
 
 func main() {
-	simple.ExecCommand("helm", map[string]interface{}{
-		// 'help' is a bogus command that 'helm' responds to with basic usage.
-		"help": &GlobalParams{},
-
-		"create":            &CreateParams{},
-		"delete":            &DeleteParams{},
-		"dependency build":  &DependencyBuildParams{},
-		"dependency list":   &DependencyListParams{},
-		"dependency update": &DependencyUpdateParams{},
-		"fetch":             &FetchParams{},
-		"get":               &GetParams{},
-		"get hooks":         &GetParams{},
-		"get manifest":      &GetParams{},
-		"get values":        &GetValuesParams{},
-		"history":           &HistoryParams{},
-		"home":              &GlobalParams{},
-		"init":              &InitParams{},
-		"inspect":           &InspectParams{},
-		"inspect chart":     &InspectParams{},
-		"inspect readme":    &InspectParams{},
-		"inspect values":    &InspectParams{},
-		"install":           &InstallParams{},
-		"lint":              &LintParams{},
-		"list":              &ListParams{},
-		"package":           &PackageParams{},
-		"plugin install":    &PluginInstallParams{},
-		"plugin list":       &GlobalParams{},
-		"plugin remove":     &PluginParams{},
-		"plugin update":     &PluginParams{},
-		"repo add":          &RepoAddParams{},
-		"repo index":        &RepoIndexParams{},
-		"repo list":         &GlobalParams{},
-		"repo remove":       &RepoRemoveParams{},
-		"repo update":       &GlobalParams{},
-		"reset":             &ResetParams{},
-		"rollback":          &RollbackParams{},
-		"search":            &SearchParams{},
-		"serve":             &ServeParams{},
-		"status":            &StatusParams{},
-		"template":          &TemplateParams{},
-		"test":              &TestParams{},
-		"upgrade":           &UpgradeParams{},
-		"verify":            &VerifyParams{},
-		"version":           &VersionParams{},
-	})
+	r := &simple.Registry{}
+
+	// 'help' is a bogus command that 'helm' responds to with basic usage.
+	simple.Register[GlobalParams](r, "help")
+
+	simple.Register[CreateParams](r, "create")
+	simple.Register[DeleteParams](r, "delete")
+	simple.Register[DependencyBuildParams](r, "dependency build")
+	simple.Register[DependencyListParams](r, "dependency list")
+	simple.Register[DependencyUpdateParams](r, "dependency update")
+	simple.Register[FetchParams](r, "fetch")
+	simple.Register[GetParams](r, "get")
+	simple.Register[GetParams](r, "get hooks")
+	simple.Register[GetParams](r, "get manifest")
+	simple.Register[GetValuesParams](r, "get values")
+	simple.Register[HistoryParams](r, "history")
+	simple.Register[GlobalParams](r, "home")
+	simple.Register[InitParams](r, "init")
+	simple.Register[InspectParams](r, "inspect")
+	simple.Register[InspectParams](r, "inspect chart")
+	simple.Register[InspectParams](r, "inspect readme")
+	simple.Register[InspectParams](r, "inspect values")
+	simple.Register[InstallParams](r, "install")
+	simple.Register[LintParams](r, "lint")
+	simple.Register[ListParams](r, "list")
+	simple.Register[PackageParams](r, "package")
+	simple.Register[PluginInstallParams](r, "plugin install")
+	simple.Register[GlobalParams](r, "plugin list")
+	simple.Register[PluginParams](r, "plugin remove")
+	simple.Register[PluginParams](r, "plugin update")
+	simple.Register[RepoAddParams](r, "repo add")
+	simple.Register[RepoIndexParams](r, "repo index")
+	simple.Register[GlobalParams](r, "repo list")
+	simple.Register[RepoRemoveParams](r, "repo remove")
+	simple.Register[GlobalParams](r, "repo update")
+	simple.Register[ResetParams](r, "reset")
+	simple.Register[RollbackParams](r, "rollback")
+	simple.Register[SearchParams](r, "search")
+	simple.Register[ServeParams](r, "serve")
+	simple.Register[StatusParams](r, "status")
+	simple.Register[TemplateParams](r, "template")
+	simple.Register[TestParams](r, "test")
+	simple.Register[UpgradeParams](r, "upgrade")
+	simple.Register[VerifyParams](r, "verify")
+	simple.Register[VersionParams](r, "version")
+
+	r.Exec("helm")
 }
@@ -0,0 +1,236 @@
+// Package fields compiles struct types into "plans" of their fields, which the
+// env and cmd packages use to find, read, and set values.  Much like
+// encoding/json does, each struct type is only walked (with reflection) once;
+// the plan is cached and re-used from then on.  Plans are also exposed for
+// tooling that needs to inspect params structs.  Since each field's name is
+// split into words, plans are cached for each set of initialisms (see
+// names.Set.Key()) as well.
+package fields
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+var (
+	cache sync.Map // map[cacheKey]*cached
+)
+
+type cacheKey struct {
+	typ reflect.Type
+	set string // the names.Set's Key()
+}
+
+type cached struct {
+	plan *Plan
+	err  error
+}
+
+// Field is a single (non-struct) field of a struct, which may be nested inside
+// inner/embedded structs.
+type Field struct {
+	StructField reflect.StructField // the field as declared in its own struct
+	Name        string              // Go field name, like "Debug"
+	Path        string              // Go path from the root struct, like "GlobalParams.Debug"
+	Index       []int               // index path from the root struct, as for FieldByIndex()
+	Depth       int                 // how deeply nested the field is (0 for the root struct)
+	Words       []string            // Name split into words (see names.Set.Split()), if possible
+}
+
+// Plan is the compiled information about a struct type's fields.
+type Plan struct {
+	Type   reflect.Type
+	Names  *names.Set // the initialisms used to split field names into words
+	Fields []*Field   // all visible fields, in field order
+
+	byName map[string]*Field
+}
+
+// AmbiguousError is returned when two fields have the same name at the same
+// depth, so that neither one hides the other.
+type AmbiguousError struct {
+	Struct string // name of the root struct
+	First  *Field
+	Second *Field
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("fields %s and %s in %s are ambiguous (same name at the same depth)", e.First.Path, e.Second.Path, e.Struct)
+}
+
+// For returns the (cached) plan for the struct type, or pointer to struct type,
+// using the default initialisms (see names.Default()).
+func For(typ reflect.Type) (plan *Plan, err error) {
+	return ForNames(typ, names.Default())
+}
+
+// ForNames is like For(), using the given initialisms to split field names
+// into words.
+func ForNames(typ reflect.Type, set *names.Set) (plan *Plan, err error) {
+	typ = TypeIndirect(typ)
+	if typ.Kind() != reflect.Struct {
+		err = fmt.Errorf("expected struct type, got %s", typ)
+		return
+	}
+
+	key := cacheKey{typ, set.Key()}
+	if c, ok := cache.Load(key); ok {
+		return c.(*cached).plan, c.(*cached).err
+	}
+
+	plan, err = compile(typ, set)
+	c, _ := cache.LoadOrStore(key, &cached{plan, err})
+	return c.(*cached).plan, c.(*cached).err
+}
+
+// Field returns the visible field with the given Go name.
+func (p *Plan) Field(name string) (f *Field, ok bool) {
+	f, ok = p.byName[name]
+	return
+}
+
+// compile walks the struct type, recursing through inner/embedded structs.
+// Like Go's own rules for embedded fields, a field hides any field of the same
+// name that is more deeply nested.  Since every inner struct's fields are
+// settings, a struct that contains itself (through a pointer) is an error.
+func compile(typ reflect.Type, set *names.Set) (plan *Plan, err error) {
+	var all []*Field
+	visiting := make(map[reflect.Type]bool) // the structs being collected
+	var collect func(typ reflect.Type, index []int, path []string)
+	collect = func(typ reflect.Type, index []int, path []string) {
+		visiting[typ] = true
+		defer delete(visiting, typ)
+
+		for i := 0; i < typ.NumField() && err == nil; i++ {
+			sf := typ.Field(i)
+			fieldIndex := append(append([]int{}, index...), i)
+			fieldPath := append(append([]string{}, path...), sf.Name)
+
+			// like encoding/json, unexported fields are left out, other than
+			// embedded structs, whose exported fields are promoted
+			if !sf.IsExported() && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+				continue
+			}
+
+			// an optional.Value is a single value, not a struct to recurse into
+			if inner := TypeIndirect(sf.Type); inner.Kind() == reflect.Struct && !optional.Is(inner) {
+				if visiting[inner] {
+					err = fmt.Errorf("field %s is a %s inside a %s (a cycle)", strings.Join(fieldPath, "."), inner, inner)
+					return
+				}
+				collect(inner, fieldIndex, fieldPath)
+				continue
+			}
+
+			words, _ := set.Split(sf.Name)
+			all = append(all, &Field{
+				StructField: sf,
+				Name:        sf.Name,
+				Path:        strings.Join(fieldPath, "."),
+				Index:       fieldIndex,
+				Depth:       len(index),
+				Words:       words,
+			})
+		}
+	}
+	collect(typ, nil, nil)
+	if err != nil {
+		return
+	}
+
+	// Find the shallowest field for each name, and drop the rest.
+	byDepth := make(map[string][]*Field)
+	for _, f := range all {
+		byDepth[f.Name] = append(byDepth[f.Name], f)
+	}
+
+	plan = &Plan{Type: typ, Names: set, byName: make(map[string]*Field)}
+	for _, f := range all {
+		same := byDepth[f.Name]
+		sort.SliceStable(same, func(i, j int) bool { return same[i].Depth < same[j].Depth })
+		if same[0] != f {
+			if same[0].Depth == f.Depth {
+				plan = nil
+				err = &AmbiguousError{Struct: typ.Name(), First: same[0], Second: f}
+				return
+			}
+			continue
+		}
+
+		plan.Fields = append(plan.Fields, f)
+		plan.byName[f.Name] = f
+	}
+
+	return
+}
+
+// Value returns the field's value from the root struct, without creating
+// anything.  Returns !ok if there is a nil pointer to an inner/embedded struct
+// along the way.  The value itself may still be a (nil) pointer.
+func (f *Field) Value(root reflect.Value) (val reflect.Value, ok bool) {
+	val = root
+	for i, index := range f.Index {
+		if i > 0 {
+			val = Indirect(val)
+			if !val.IsValid() {
+				return
+			}
+		}
+		val = val.Field(index)
+	}
+	ok = true
+	return
+}
+
+// Ensure returns the field's actual (non-pointer) value from the root struct,
+// creating any pointers along the way (including the field's own) as needed.
+// Returns !ok if the value cannot be set (an unexported field, for example).
+func (f *Field) Ensure(root reflect.Value) (val reflect.Value, ok bool) {
+	val = root
+	for _, index := range f.Index {
+		val = val.Field(index)
+		if !val.CanSet() {
+			return
+		}
+		val = Ensure(val)
+	}
+	ok = true
+	return
+}
+
+// Ensure takes a cue from encoding/json's decode.go helper 'indirect' that
+// does something similar... given a settable value (which may be a pointer),
+// creates the underlying data when needed, and returns the actual settable
+// value.
+func Ensure(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			val.Set(reflect.New(val.Type().Elem()))
+		}
+		val = val.Elem()
+	}
+	return val
+}
+
+// Indirect follows any pointers to the underlying value.  The result is not
+// valid if any of the pointers is nil.
+func Indirect(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	return val
+}
+
+// TypeIndirect follows any pointer types to the underlying type.
+func TypeIndirect(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
@@ -0,0 +1,180 @@
+package fields
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/names"
+)
+
+type Global struct {
+	Debug bool
+	Host  string
+}
+
+type TLS struct {
+	TLS     bool
+	TLSCert string
+}
+
+type Get struct {
+	*Global
+	Revision int
+	TLS
+}
+
+type GetValues struct {
+	All bool
+	Get
+	Debug bool // hides Global.Debug
+}
+
+func TestFor(t *testing.T) {
+	plan, err := For(reflect.TypeOf(&GetValues{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"All", "Get.Global.Host", "Get.Revision", "Get.TLS.TLS", "Get.TLS.TLSCert", "Debug"}
+	if len(plan.Fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(plan.Fields))
+	}
+	for i, f := range plan.Fields {
+		if f.Path != expected[i] {
+			t.Errorf("[%d] expected %q, got %q", i, expected[i], f.Path)
+		}
+	}
+
+	f, ok := plan.Field("TLSCert")
+	if !ok {
+		t.Fatal("expected to find TLSCert")
+	}
+	if f.Depth != 2 {
+		t.Errorf("expected depth 2, got %d", f.Depth)
+	}
+	if !reflect.DeepEqual(f.Index, []int{1, 2, 1}) {
+		t.Errorf("unexpected index %v", f.Index)
+	}
+	if !reflect.DeepEqual(f.Words, []string{"TLS", "Cert"}) {
+		t.Errorf("unexpected words %q", f.Words)
+	}
+
+	debug, _ := plan.Field("Debug")
+	if debug.Depth != 0 {
+		t.Errorf("expected outer Debug to hide inner one, got %s", debug.Path)
+	}
+}
+
+type inner struct {
+	Token string
+	key   string
+}
+
+func TestForUnexported(t *testing.T) {
+	plan, err := For(reflect.TypeOf(struct {
+		Name       string
+		maxRetries int
+		settings   Global
+		inner
+	}{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"Name", "inner.Token"}
+	if len(plan.Fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(plan.Fields))
+	}
+	for i, f := range plan.Fields {
+		if f.Path != expected[i] {
+			t.Errorf("[%d] expected %q, got %q", i, expected[i], f.Path)
+		}
+	}
+}
+
+func TestForCached(t *testing.T) {
+	first, _ := For(reflect.TypeOf(GetValues{}))
+	second, _ := For(reflect.TypeOf(&GetValues{}))
+	if first != second {
+		t.Error("expected the same (cached) plan")
+	}
+
+	// an identical set, like the one Register() builds, re-uses the plan
+	same, _ := ForNames(reflect.TypeOf(GetValues{}), names.Default().With())
+	if same != first {
+		t.Error("expected the same (cached) plan for an identical set")
+	}
+	other, _ := ForNames(reflect.TypeOf(GetValues{}), names.Default().With("OCI"))
+	if other == first {
+		t.Error("expected a different plan for a different set")
+	}
+}
+
+func TestForErrors(t *testing.T) {
+	_, err := For(reflect.TypeOf(42))
+	if err == nil {
+		t.Error("missing expected error for non-struct")
+	}
+
+	_, err = For(reflect.TypeOf(struct {
+		Global
+		Other struct{ Host string }
+	}{}))
+	var ambiguous *AmbiguousError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected *AmbiguousError, got %v", err)
+	}
+	if ambiguous.First.Path != "Global.Host" || ambiguous.Second.Path != "Other.Host" {
+		t.Errorf("unexpected fields: %s, %s", ambiguous.First.Path, ambiguous.Second.Path)
+	}
+}
+
+type cycleNode struct {
+	Name  string
+	Inner struct{ Next *cycleNode }
+}
+
+func TestForCycle(t *testing.T) {
+	_, err := For(reflect.TypeOf(cycleNode{}))
+	expected := "field Inner.Next is a fields.cycleNode inside a fields.cycleNode (a cycle)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+
+	// the same struct in two places (not inside itself) is fine
+	_, err = For(reflect.TypeOf(struct {
+		Global
+		Backup struct{ Global }
+	}{}))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValueAndEnsure(t *testing.T) {
+	plan, _ := For(reflect.TypeOf(GetValues{}))
+	host, _ := plan.Field("Host")
+
+	params := &GetValues{}
+	root := reflect.ValueOf(params).Elem()
+
+	if _, ok := host.Value(root); ok {
+		t.Error("expected nil embedded pointer to stop Value()")
+	}
+
+	val, ok := host.Ensure(root)
+	if !ok {
+		t.Fatal("unexpected failure from Ensure()")
+	}
+	val.SetString("example.com")
+
+	if params.Global == nil || params.Host != "example.com" {
+		t.Errorf("Ensure() did not create embedded struct: %+v", params.Global)
+	}
+
+	val, ok = host.Value(root)
+	if !ok || val.String() != "example.com" {
+		t.Errorf("unexpected value from Value(): %v", val)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/JaredReisinger/drone-plugin-helper/cmd"
 	"github.com/JaredReisinger/drone-plugin-helper/env"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
 )
 
 const (
@@ -21,29 +22,27 @@ func main() {
 	log.Println("parsing values...")
 	// cfg := &config{Embedded: &Embedded{}}
 	cfg := &config{}
-	unused, err := env.Parse(vars, cfg)
+	unused, md, err := env.ParseWithMetadata(vars, cfg)
 	if err != nil {
 		log.Printf("error: %+v", err)
 		return
 	}
 	log.Printf("parsed: %+v", cfg)
 	log.Printf("unused: %+v", unused)
+	log.Printf("set: %+v", md.Fields())
 
 	log.Printf("Inner value: %q (%q)", cfg.Inner, cfg.Embedded.Inner)
-	if cfg.IntPtr != nil {
-		log.Printf("***IntPtr value: %d", ***cfg.IntPtr)
-	} else {
-		log.Printf("IntPtr value: <nil>")
-	}
+	log.Printf("Int value: %d (set: %t)", cfg.Int, md.IsSet("Int"))
+	log.Printf("Count value: %d (set: %t)", cfg.Count.Or(-1), cfg.Count.IsSet())
 
 	log.Println("")
 	log.Println("creating command line...")
-	line, err := cmd.Create(cfg)
+	line, err := cmd.CreateWithMetadata(cfg, md)
 	if err != nil {
 		log.Printf("error: %+v", err)
 		return
 	}
-	log.Printf("cmdline: %q", line)
+	log.Printf("cmdline: %s", cmd.Quote(line))
 }
 
 type Embedded struct {
@@ -55,7 +54,8 @@ type config struct {
 
 	*Embedded
 
-	IntPtr ***int
+	Int   int                 // emitted even when explicitly set to 0
+	Count optional.Value[int] // knows whether it was set, without metadata
 
 	// Int    int    `cmd:"--int"`
 	Int8  int8  `cmd:"--int8"`
@@ -0,0 +1,117 @@
+package names
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Convention joins the words of a name (as returned by Split()) into a
+// particular case convention.  Implementations should be comparable (like
+// Case), since they are used as part of cache keys.
+type Convention interface {
+	Join(words []string) string
+}
+
+// Case is one of the built-in case conventions.
+type Case int
+
+const (
+	// Kebab is lower-case words joined with hyphens: "tiller-connection-timeout".
+	Kebab Case = iota
+	// Snake is lower-case words joined with underscores: "tiller_connection_timeout".
+	Snake
+	// Camel is title-case words with a lower-case first word: "tillerConnectionTimeout".
+	Camel
+	// Pascal is title-case words: "TillerConnectionTimeout".
+	Pascal
+	// Dot is lower-case words joined with dots: "tiller.connection.timeout".
+	Dot
+	// ScreamingSnake is upper-case words joined with underscores: "TILLER_CONNECTION_TIMEOUT".
+	ScreamingSnake
+)
+
+var caseNames = []string{"kebab", "snake", "camel", "pascal", "dot", "screaming-snake"}
+
+func (c Case) String() string {
+	if c < 0 || int(c) >= len(caseNames) {
+		return fmt.Sprintf("Case(%d)", int(c))
+	}
+	return caseNames[c]
+}
+
+// Join joins the words in the case convention.  Initialisms are treated like
+// any other word, so "ServerURL" is "serverUrl" in Camel, and "ServerUrl" in
+// Pascal.
+func (c Case) Join(words []string) string {
+	converted := make([]string, len(words))
+	for i, word := range words {
+		switch {
+		case c == ScreamingSnake:
+			converted[i] = strings.ToUpper(word)
+		case c == Pascal || (c == Camel && i > 0):
+			converted[i] = title(word)
+		default:
+			converted[i] = strings.ToLower(word)
+		}
+	}
+
+	switch c {
+	case Snake, ScreamingSnake:
+		return strings.Join(converted, "_")
+	case Camel, Pascal:
+		return strings.Join(converted, "")
+	case Dot:
+		return strings.Join(converted, ".")
+	}
+	return strings.Join(converted, "-")
+}
+
+// title returns the word with an upper-case first letter, and the rest in
+// lower-case.
+func title(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + strings.ToLower(word[size:])
+}
+
+// Convert splits the name (see Split()) and joins the words in the
+// convention.
+func (s *Set) Convert(name string, convention Convention) (string, error) {
+	words, err := s.Split(name)
+	if err != nil {
+		return "", err
+	}
+	return convention.Join(words), nil
+}
+
+// ToKebab converts a Go name to kebab-case: "TLSCertID" is "tls-cert-id".
+func ToKebab(name string) (string, error) {
+	return Default().Convert(name, Kebab)
+}
+
+// ToSnake converts a Go name to snake_case: "TLSCertID" is "tls_cert_id".
+func ToSnake(name string) (string, error) {
+	return Default().Convert(name, Snake)
+}
+
+// ToCamel converts a Go name to camelCase: "TLSCertID" is "tlsCertId".
+func ToCamel(name string) (string, error) {
+	return Default().Convert(name, Camel)
+}
+
+// ToPascal converts a Go name to PascalCase: "TLSCertID" is "TlsCertId".
+func ToPascal(name string) (string, error) {
+	return Default().Convert(name, Pascal)
+}
+
+// ToDot converts a Go name to dot.case: "TLSCertID" is "tls.cert.id".
+func ToDot(name string) (string, error) {
+	return Default().Convert(name, Dot)
+}
+
+// ToScreamingSnake converts a Go name to SCREAMING_SNAKE_CASE: "TLSCertID" is
+// "TLS_CERT_ID".
+func ToScreamingSnake(name string) (string, error) {
+	return Default().Convert(name, ScreamingSnake)
+}
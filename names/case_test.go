@@ -0,0 +1,52 @@
+package names
+
+import (
+	"testing"
+)
+
+func TestConversions(t *testing.T) {
+	conversions := []struct {
+		name string
+		fn   func(string) (string, error)
+	}{
+		{"kebab", ToKebab},
+		{"snake", ToSnake},
+		{"camel", ToCamel},
+		{"pascal", ToPascal},
+		{"dot", ToDot},
+		{"screaming-snake", ToScreamingSnake},
+	}
+
+	for _, ex := range []struct {
+		input    string
+		expected []string // in the same order as the conversions
+	}{
+		{"TillerConnectionTimeout", []string{"tiller-connection-timeout", "tiller_connection_timeout", "tillerConnectionTimeout", "TillerConnectionTimeout", "tiller.connection.timeout", "TILLER_CONNECTION_TIMEOUT"}},
+		{"TLSCertID", []string{"tls-cert-id", "tls_cert_id", "tlsCertId", "TlsCertId", "tls.cert.id", "TLS_CERT_ID"}},
+		{"S3Bucket", []string{"s3-bucket", "s3_bucket", "s3Bucket", "S3Bucket", "s3.bucket", "S3_BUCKET"}},
+		{"maxRetries", []string{"max-retries", "max_retries", "maxRetries", "MaxRetries", "max.retries", "MAX_RETRIES"}},
+		{"Debug", []string{"debug", "debug", "debug", "Debug", "debug", "DEBUG"}},
+	} {
+		for i, conversion := range conversions {
+			actual, err := conversion.fn(ex.input)
+			if err != nil {
+				t.Errorf("unexpected error for %s(%q): %v", conversion.name, ex.input, err)
+			} else if actual != ex.expected[i] {
+				t.Errorf("%s(%q): expected %q, got %q", conversion.name, ex.input, ex.expected[i], actual)
+			}
+		}
+	}
+
+	if _, err := ToKebab("_"); err == nil {
+		t.Error("missing expected error")
+	}
+}
+
+func TestCaseString(t *testing.T) {
+	if actual := ScreamingSnake.String(); actual != "screaming-snake" {
+		t.Errorf("expected %q, got %q", "screaming-snake", actual)
+	}
+	if actual := Case(42).String(); actual != "Case(42)" {
+		t.Errorf("expected %q, got %q", "Case(42)", actual)
+	}
+}
@@ -3,14 +3,19 @@ package names
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // list of initialisms taken from
 // https://github.com/golang/lint/blob/master/lint.go#L771-L810
 
 var (
-	commonInitialisms = []string{
+	// CommonInitialisms are the initialisms known by default, taken from
+	// golint's list.
+	CommonInitialisms = []string{
 		"ACL",
 		"API",
 		"ASCII",
@@ -51,38 +56,186 @@ var (
 		"XSS",
 	}
 
-	initialismRe  = regexp.MustCompile(fmt.Sprintf("^(?i:(%s))+$", strings.Join(commonInitialisms, "|")))
-	initialismMap map[string]bool
+	// CloudInitialisms are additional initialisms common in cloud and devops
+	// tooling.  They are *not* known by default, since they change how some
+	// existing names are split ("TLSCaCert" must become "TLSCACert" once "CA"
+	// is an initialism); opt in with Register(CloudInitialisms...), or with
+	// Default().With(CloudInitialisms...) for a single decoder or encoder.
+	CloudInitialisms = []string{
+		"ACR",
+		"AKS",
+		"AMI",
+		"ARN",
+		"AWS",
+		"CA",
+		"CD",
+		"CDN",
+		"CI",
+		"CLI",
+		"CSR",
+		"DB",
+		"ECR",
+		"ECS",
+		"EKS",
+		"GCP",
+		"GCR",
+		"GCS",
+		"GKE",
+		"GPG",
+		"IAM",
+		"JWT",
+		"K8S",
+		"KMS",
+		"OCI",
+		"OIDC",
+		"PEM",
+		"RBAC",
+		"S3",
+		"SDK",
+		"SHA",
+		"SSL",
+		"SSO",
+		"VPC",
+		"YAML",
+	}
+
+	defaultSet atomic.Pointer[Set]
+	registerMu sync.Mutex
 )
 
 func init() {
-	initialismMap = make(map[string]bool, len(commonInitialisms))
-	for _, s := range commonInitialisms {
-		initialismMap[s] = true
+	defaultSet.Store(NewSet(CommonInitialisms...))
+}
+
+// Set is a set of known initialisms, along with the matchers that split names
+// using them.  A Set is immutable, and therefore safe for concurrent use;
+// With() returns a new Set rather than changing an existing one.
+type Set struct {
+	key          string
+	list         []string
+	initialisms  map[string]bool
+	initialismRe *regexp.Regexp
+}
+
+// NewSet returns a Set of exactly the given initialisms.  (Most callers want
+// Default().With() instead, to extend the default initialisms.)
+func NewSet(initialisms ...string) *Set {
+	s := &Set{initialisms: make(map[string]bool, len(initialisms))}
+	for _, initialism := range initialisms {
+		initialism = strings.ToUpper(initialism)
+		if initialism == "" || s.initialisms[initialism] {
+			continue
+		}
+		s.initialisms[initialism] = true
+		s.list = append(s.list, initialism)
+	}
+	sort.Strings(s.list)
+	s.key = strings.Join(s.list, ",")
+
+	quoted := make([]string, 0, len(s.list))
+	for _, initialism := range s.list {
+		quoted = append(quoted, regexp.QuoteMeta(initialism))
+	}
+	alternatives := strings.Join(quoted, "|")
+	if alternatives == "" {
+		// an empty group would match the empty string
+		alternatives = "[^\\x00-\\x{10FFFF}]"
 	}
+
+	s.initialismRe = regexp.MustCompile(fmt.Sprintf("^(?i:(%s))+$", alternatives))
+	return s
+}
+
+// With returns a new Set with the additional initialisms.
+func (s *Set) With(initialisms ...string) *Set {
+	return NewSet(append(append([]string{}, s.list...), initialisms...)...)
+}
+
+// Key identifies the initialisms in the set: two sets with the same
+// initialisms have the same key, however they were built.  Anything derived
+// from a set (like the cached field plans in the env and cmd packages) is
+// keyed by it, so that a new but identical set re-uses the same work.
+func (s *Set) Key() string {
+	return s.key
+}
+
+// Initialisms returns the (upper-case) initialisms in the set, sorted.
+func (s *Set) Initialisms() []string {
+	return append([]string{}, s.list...)
+}
+
+// Default returns the set of initialisms used by the package-level functions
+// (and by default, by the env and cmd packages).
+func Default() *Set {
+	return defaultSet.Load()
+}
+
+// Register adds initialisms to the default set.  Since anything derived from
+// the previous set (like the cached field plans in the env and cmd packages)
+// is keyed by the set's Key(), it is safe to call Register() at any time, although
+// calling it from init() or early in main() avoids surprises.
+func Register(initialisms ...string) {
+	registerMu.Lock()
+	defer registerMu.Unlock()
+	defaultSet.Store(Default().With(initialisms...))
 }
 
 // IsInitialism returns whether the given term is one of the known common
 // initialisms (like HTML or JSON).  It will *not* return true for concatenated
 // values, like "XMLID".  See SplitInitialisms() for that.
 func IsInitialism(term string) bool {
-	is := initialismMap[strings.ToUpper(term)]
+	return Default().IsInitialism(term)
+}
+
+// IsInitialism is like the package-level IsInitialism(), using the initialisms
+// in the set.
+func (s *Set) IsInitialism(term string) bool {
+	is := s.initialisms[strings.ToUpper(term)]
 	return is
 }
 
+// IsPluralInitialism returns whether the given term is one of the known
+// initialisms followed by a (case-insensitive) "s", like "IDs", "URLS" or
+// "ips".  Go style writes these with a lower-case "s" ("ImageIDs").  A term
+// that is itself an initialism, like "HTTPS", is not a plural.
+func IsPluralInitialism(term string) bool {
+	return Default().IsPluralInitialism(term)
+}
+
+// IsPluralInitialism is like the package-level IsPluralInitialism(), using
+// the initialisms in the set.
+func (s *Set) IsPluralInitialism(term string) bool {
+	singular, ok := trimPlural(term)
+	return ok && s.IsInitialism(singular) && !s.IsInitialism(term)
+}
+
+// trimPlural removes a trailing "s" (or "S") from the term.
+func trimPlural(term string) (string, bool) {
+	if len(term) < 2 || (term[len(term)-1] != 's' && term[len(term)-1] != 'S') {
+		return term, false
+	}
+	return term[:len(term)-1], true
+}
+
 // SplitInitialisms attemps to split concatenated initialisms, like "XMLID" into
 // ["XML", "ID"].  There are theoritically concerns about shared prefixes and
 // suffixes; for example, is "HTTPSQL" meant to be ["HTTP", "SQL"] or
 // ["HTTPS", "QL"]?  Fortunately, only one interpretation successfully consumes
 // all of the input text.
 func SplitInitialisms(input string) (terms []string, err error) {
+	return Default().SplitInitialisms(input)
+}
+
+// SplitInitialisms is like the package-level SplitInitialisms(), using the
+// initialisms in the set.
+func (s *Set) SplitInitialisms(input string) (terms []string, err error) {
 	// Go regexps don't return complex grouping matches... for example, given
 	// "^(a|b)+$", FindAllStringSubmatch("aab") returns ["aab", "b"], where "b" is
 	// the *last* match of the group.  But, we can trim the from the end of the
 	// string and repeat until we finish.  In practice, we'll only have a couple
 	// of iterations, so this isn't too bad.
 	for input != "" {
-		m := initialismRe.FindAllStringSubmatch(input, -1)
+		m := s.initialismRe.FindAllStringSubmatch(input, -1)
 		if m == nil {
 			err = fmt.Errorf("input does not match initialism expression: %q", input)
 			return
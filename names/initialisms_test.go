@@ -25,6 +25,27 @@ func TestIsInitialism(t *testing.T) {
 	}
 }
 
+func TestIsPluralInitialism(t *testing.T) {
+	for _, ex := range []struct {
+		input    string
+		expected bool
+	}{
+		{"IDs", true},
+		{"IDS", true},
+		{"ids", true},
+		{"URLs", true},
+		{"ID", false},
+		{"HTTPS", false},
+		{"s", false},
+		{"Nopes", false},
+	} {
+		actual := IsPluralInitialism(ex.input)
+		if actual != ex.expected {
+			t.Errorf("for input %q, expected %v, got %v", ex.input, ex.expected, actual)
+		}
+	}
+}
+
 func TestSplitInitialisms(t *testing.T) {
 	for _, ex := range []struct {
 		input    string
@@ -65,3 +86,85 @@ func equalStringSlices(expected, actual []string, t *testing.T) {
 		t.Errorf("missing expected values: %q", expected[a:])
 	}
 }
+
+func TestSet(t *testing.T) {
+	s := Default().With(CloudInitialisms...)
+
+	if !s.IsInitialism("k8s") || !s.IsInitialism("HTTP") {
+		t.Error("expected extended set to include cloud and common initialisms")
+	}
+	if IsInitialism("K8S") {
+		t.Error("expected default set to be unchanged")
+	}
+
+	for _, ex := range []struct {
+		input    string
+		expected []string
+	}{
+		{"TLSCACert", []string{"TLS", "CA", "Cert"}},
+		{"AWSKMSKeyID", []string{"AWS", "KMS", "Key", "ID"}},
+		{"S3Bucket", []string{"S3", "Bucket"}},
+		{"K8SNamespace", []string{"K8S", "Namespace"}},
+		{"GPGKey", []string{"GPG", "Key"}},
+	} {
+		actual, err := s.Split(ex.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %+v", ex.input, err)
+			continue
+		}
+		equalStringSlices(ex.expected, actual, t)
+	}
+
+	// without the cloud initialisms, "AWSKMS" is a single unknown word
+	actual, _ := Split("AWSKMSKeyID")
+	equalStringSlices([]string{"AWSKMS", "Key", "ID"}, actual, t)
+}
+
+func TestNewSetEmpty(t *testing.T) {
+	s := NewSet()
+	if _, err := s.SplitInitialisms("HTTP"); err == nil {
+		t.Error("missing expected error")
+	}
+	actual, err := s.Split("TwoWords")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equalStringSlices([]string{"Two", "Words"}, actual, t)
+}
+
+func TestSetKey(t *testing.T) {
+	if Default().With().Key() != Default().Key() {
+		t.Error("expected identical sets to have the same key")
+	}
+	if NewSet("b", "A").Key() != NewSet("a", "B", "a").Key() {
+		t.Error("expected key to ignore order, case and duplicates")
+	}
+	if Default().With("OCI").Key() == Default().Key() {
+		t.Error("expected a different key for different initialisms")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	before := Default()
+	defer defaultSet.Store(before)
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			IsInitialism("OCI")
+		}
+		done <- true
+	}()
+	Register("OCI")
+	<-done
+
+	if !IsInitialism("oci") {
+		t.Error("expected registered initialism")
+	}
+	if Default() == before {
+		t.Error("expected a new default set")
+	}
+	if before.IsInitialism("OCI") {
+		t.Error("expected the previous set to be unchanged")
+	}
+}
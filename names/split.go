@@ -2,37 +2,146 @@ package names
 
 import (
 	"fmt"
-	"regexp"
-	"strings"
-)
-
-var (
-	wordsRe = regexp.MustCompile(fmt.Sprintf("^((?:[[:upper:]][^[:upper:]]+)|%s)+$", strings.Join(commonInitialisms, "|")))
+	"unicode"
 )
 
 // Split splits names based on Go's naming rules.
 func Split(input string) (terms []string, err error) {
-	// Go regexps don't return complex grouping matches... for example, given
-	// "^(a|b)+$", FindAllStringSubmatch("aab") returns ["aab", "b"], where "b" is
-	// the *last* match of the group.  But, we can trim the from the end of the
-	// string and repeat until we finish.  In practice, we'll only have a couple
-	// of iterations, so this isn't too bad.
-	for input != "" {
-		m := wordsRe.FindAllStringSubmatch(input, -1)
-		if m == nil {
+	return Default().Split(input)
+}
+
+// Split is like the package-level Split(), using the initialisms in the set.
+// Words start at an upper-case letter, and a run of upper-case letters is split
+// into known initialisms where possible ("XMLHTTPRequest" becomes "XML",
+// "HTTP", "Request").  A name may start with a lower-case word, as unexported
+// names do ("maxRetries" becomes "max", "Retries").  Digits stay with the
+// word they follow ("S3", "Ipv6", "Oauth2", "Server2"), and lower-case letters
+// after digits continue that word ("Md5sum").  Lower-case letters directly
+// after a run of initialisms stay with the last one ("IPv6"), which also keeps
+// plural initialisms whole ("ImageIDs" becomes "Image", "IDs").  Underscores
+// separate words, and are dropped.
+func (s *Set) Split(input string) (terms []string, err error) {
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '_':
+			i++
+
+		case isLower(r):
+			// a lower-case word, only expected at the start of a name
+			end := wordTail(runes, i)
+			terms = append(terms, string(runes[i:end]))
+			i = end
+
+		case unicode.IsUpper(r):
+			var words []string
+			words, i = s.upperWords(runes, i)
+			terms = append(terms, words...)
+
+		default:
+			// digits can't start a Go name, and are otherwise always consumed
+			// by the preceding word
 			err = fmt.Errorf("input does not match words expression: %q", input)
 			return
 		}
+	}
 
-		final := m[0][1]
-		terms = append(terms, final)
-		input = strings.TrimSuffix(input, final)
+	if len(terms) == 0 {
+		err = fmt.Errorf("input does not match words expression: %q", input)
 	}
+	return
+}
+
+// upperWords splits the word(s) starting with the upper-case letter at start,
+// returning the words and the index after them.
+func (s *Set) upperWords(runes []rune, start int) (words []string, end int) {
+	// find the run of upper-case letters and digits
+	end = start
+	for end < len(runes) && (unicode.IsUpper(runes[end]) || unicode.IsDigit(runes[end])) {
+		end++
+	}
+	run := string(runes[start:end])
+	followedByLower := end < len(runes) && isLower(runes[end])
 
-	// recall that we appended in reverse order, so we need to reverse the list
-	for i, j := 0, len(terms)-1; i < j; i, j = i+1, j-1 {
-		terms[i], terms[j] = terms[j], terms[i]
+	// a run at the end of the name (or before an underscore) is made of
+	// initialisms, like "XMLID", or is a single word, like "K8S"
+	if !followedByLower {
+		words = s.splitRun(run)
+		return
 	}
 
+	last := end - 1
+	if unicode.IsDigit(runes[last]) {
+		// like "V2beta": the lower-case letters continue the run's last word
+		words = s.splitRun(run)
+		tail := wordTail(runes, end)
+		words[len(words)-1] += string(runes[end:tail])
+		end = tail
+		return
+	}
+
+	// Usually the last upper-case letter starts the next word ("HTTPServer" is
+	// "HTTP" and "Server").  But if the whole run is made of initialisms and
+	// either the run without its last letter is not ("IPv6"), or the only
+	// lower-case letter is a plural "s" ("UIDs", rather than "UI" and "Ds"),
+	// the lower-case letters belong to the last initialism instead.
+	head := string(runes[start:last])
+	plural := wordTail(runes, end) == end+1 && runes[end] == 's'
+	if _, headErr := s.SplitInitialisms(head); last > start && (headErr != nil || plural) {
+		if all, err := s.SplitInitialisms(run); err == nil {
+			words = all
+			tail := wordTail(runes, end)
+			words[len(words)-1] += string(runes[end:tail])
+			end = tail
+			return
+		}
+	}
+
+	if head != "" {
+		words = s.splitRun(head)
+	}
+	tail := wordTail(runes, last+1)
+	words = append(words, string(runes[last:tail]))
+	end = tail
 	return
 }
+
+// splitRun splits a run of upper-case letters and digits into initialisms, if
+// possible.  Trailing digits stay with the last initialism ("SHA256").  If the
+// run can't be split, it is a single word.
+func (s *Set) splitRun(run string) []string {
+	if terms, err := s.SplitInitialisms(run); err == nil {
+		return terms
+	}
+
+	letters := []rune(run)
+	digits := len(letters)
+	for digits > 0 && unicode.IsDigit(letters[digits-1]) {
+		digits--
+	}
+	if digits > 0 && digits < len(letters) {
+		if terms, err := s.SplitInitialisms(string(letters[:digits])); err == nil {
+			terms[len(terms)-1] += string(letters[digits:])
+			return terms
+		}
+	}
+
+	return []string{run}
+}
+
+// wordTail returns the end of the word whose lower-case letters start at i:
+// lower-case letters, and digits (followed by more lower-case letters).
+func wordTail(runes []rune, i int) int {
+	for i < len(runes) && (isLower(runes[i]) || unicode.IsDigit(runes[i])) {
+		i++
+	}
+	return i
+}
+
+// isLower reports whether r is a letter that doesn't start a new word:
+// lower-case, or a letter without case at all.
+func isLower(r rune) bool {
+	return unicode.IsLetter(r) && !unicode.IsUpper(r)
+}
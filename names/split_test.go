@@ -1,9 +1,6 @@
 package names
 
 import (
-	// "fmt"
-	// "regexp"
-	// "strings"
 	"testing"
 )
 
@@ -12,20 +9,79 @@ func TestSplit(t *testing.T) {
 		input    string
 		expected []string
 	}{
+		// simple words
 		{"Simple", []string{"Simple"}},
 		{"TwoWords", []string{"Two", "Words"}},
+		{"A", []string{"A"}},
+		{"Xml", []string{"Xml"}},
+
+		// initialisms
 		{"HTTP", []string{"HTTP"}},
 		{"HTTPSQL", []string{"HTTP", "SQL"}},
 		{"HTTPSSSH", []string{"HTTPS", "SSH"}},
 		{"XMLIDID", []string{"XML", "ID", "ID"}},
 		{"TLSCertID", []string{"TLS", "Cert", "ID"}},
-		{"nope", nil},
+		{"TLSCaCert", []string{"TLS", "Ca", "Cert"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"XMLHTTPRequest", []string{"XML", "HTTP", "Request"}},
+		{"ServerURL", []string{"Server", "URL"}},
+		{"APIVersion", []string{"API", "Version"}},
+
+		// plural initialisms
+		{"IDs", []string{"IDs"}},
+		{"ImageIDs", []string{"Image", "IDs"}},
+		{"UIDs", []string{"UIDs"}},
+		{"XMLIDs", []string{"XML", "IDs"}},
+		{"URLsFile", []string{"URLs", "File"}},
+		{"AllowedIPs", []string{"Allowed", "IPs"}},
+		{"HTTPSettings", []string{"HTTP", "Settings"}},
+
+		// unknown upper-case runs
+		{"ABTest", []string{"AB", "Test"}},
+		{"GPUCount", []string{"GPU", "Count"}},
+		{"UseGPU", []string{"Use", "GPU"}},
+
+		// digits
+		{"S3Bucket", []string{"S3", "Bucket"}},
+		{"Ipv6", []string{"Ipv6"}},
+		{"IPv6", []string{"IPv6"}},
+		{"IPv6Address", []string{"IPv6", "Address"}},
+		{"Oauth2Token", []string{"Oauth2", "Token"}},
+		{"Base64Data", []string{"Base64", "Data"}},
+		{"HTTPServer2Port", []string{"HTTP", "Server2", "Port"}},
+		{"ThreeWith1Number", []string{"Three", "With1", "Number"}},
+		{"HTTP2", []string{"HTTP2"}},
+		{"HTTP2Server", []string{"HTTP2", "Server"}},
+		{"UTF8String", []string{"UTF8", "String"}},
+		{"SHA256Sum", []string{"SHA256", "Sum"}},
+		{"K8SNamespace", []string{"K8S", "Namespace"}},
+		{"V2beta1", []string{"V2beta1"}},
+		{"Md5sum", []string{"Md5sum"}},
+		{"Retries3", []string{"Retries3"}},
+
+		// lower-case (unexported) names
+		{"nope", []string{"nope"}},
+		{"invalidFormat", []string{"invalid", "Format"}},
+		{"maxRetries", []string{"max", "Retries"}},
+		{"ipv6Address", []string{"ipv6", "Address"}},
+		{"httpURL", []string{"http", "URL"}},
+
+		// underscores
+		{"Some_Name", []string{"Some", "Name"}},
+		{"_private", []string{"private"}},
+
+		// failures
+		{"", nil},
+		{"_", nil},
+		{"Dashed-Name", nil},
 	} {
 		actual, err := Split(ex.input)
 		if err != nil {
 			if ex.expected != nil {
 				t.Errorf("unexpected error for %q: %+v", ex.input, err)
 			}
+		} else if ex.expected == nil {
+			t.Errorf("missing expected error for %q, got %q", ex.input, actual)
 		} else {
 			// deep compare?
 			equalStringSlices(ex.expected, actual, t)
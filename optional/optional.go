@@ -0,0 +1,112 @@
+// Package optional provides a generic Value type that can tell an unset value
+// apart from one that was set to its "zero value", without resorting to
+// pointer fields (and all of their nil checks).  The env and cmd packages both
+// understand Value fields: env.Parse() sets them (and marks them as set) when
+// a variable is present, and cmd.Create() only emits them when they are set.
+package optional
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Value holds a value of type T, and whether it has been set.  The zero Value
+// is unset.
+type Value[T any] struct {
+	value T
+	set   bool
+}
+
+// Of returns a Value that is set to value.
+func Of[T any](value T) Value[T] {
+	return Value[T]{value, true}
+}
+
+// IsSet reports whether the value has been set, even if it was set to the
+// "zero value" for T.
+func (v Value[T]) IsSet() bool {
+	return v.set
+}
+
+// Get returns the value, or the "zero value" for T if it has not been set.
+func (v Value[T]) Get() T {
+	return v.value
+}
+
+// Or returns the value if it has been set, and def otherwise.
+func (v Value[T]) Or(def T) T {
+	if !v.set {
+		return def
+	}
+	return v.value
+}
+
+// Set sets the value.
+func (v *Value[T]) Set(value T) {
+	v.value = value
+	v.set = true
+}
+
+// Unset clears the value.
+func (v *Value[T]) Unset() {
+	var zero T
+	v.value = zero
+	v.set = false
+}
+
+// String returns the value formatted with "%v", or "<unset>".
+func (v Value[T]) String() string {
+	if !v.set {
+		return "<unset>"
+	}
+	return fmt.Sprintf("%v", v.value)
+}
+
+// Reflect returns the underlying value for reflection-based packages, which
+// can set it directly (and then call MarkSet()).
+func (v *Value[T]) Reflect() reflect.Value {
+	return reflect.ValueOf(&v.value).Elem()
+}
+
+// MarkSet marks the value as set, after it has been set via Reflect().
+func (v *Value[T]) MarkSet() {
+	v.set = true
+}
+
+// Optional is implemented by a pointer to any Value, and allows
+// reflection-based packages to get and set the value without knowing T.
+type Optional interface {
+	IsSet() bool
+	Reflect() reflect.Value
+	MarkSet()
+}
+
+var optionalType = reflect.TypeOf((*Optional)(nil)).Elem()
+
+// Is reports whether typ is a Value type (of any T).
+func Is(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && reflect.PointerTo(typ).Implements(optionalType)
+}
+
+// ElemType returns T for a Value[T] type, or !ok if typ isn't a Value type.
+func ElemType(typ reflect.Type) (elem reflect.Type, ok bool) {
+	if !Is(typ) {
+		return
+	}
+	return reflect.New(typ).Interface().(Optional).Reflect().Type(), true
+}
+
+// From returns the Optional for a Value, or !ok if val isn't one, or can't be
+// used (like an unexported field).  If val is addressable, changes through the
+// Optional are made to val itself; otherwise, they are made to a copy.
+func From(val reflect.Value) (o Optional, ok bool) {
+	if !val.IsValid() || !val.CanInterface() || !Is(val.Type()) {
+		return
+	}
+	if !val.CanAddr() {
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		val = ptr.Elem()
+	}
+	return val.Addr().Interface().(Optional), true
+}
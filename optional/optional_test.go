@@ -0,0 +1,93 @@
+package optional
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	var v Value[int]
+	if v.IsSet() {
+		t.Error("expected zero Value to be unset")
+	}
+	if actual := v.Or(42); actual != 42 {
+		t.Errorf("expected %d, got %d", 42, actual)
+	}
+	if actual := v.String(); actual != "<unset>" {
+		t.Errorf("expected %q, got %q", "<unset>", actual)
+	}
+
+	v.Set(0)
+	if !v.IsSet() {
+		t.Error("expected Value to be set")
+	}
+	if actual := v.Or(42); actual != 0 {
+		t.Errorf("expected %d, got %d", 0, actual)
+	}
+
+	v.Unset()
+	if v.IsSet() {
+		t.Error("expected Value to be unset")
+	}
+
+	if actual := Of("x").Get(); actual != "x" {
+		t.Errorf("expected %q, got %q", "x", actual)
+	}
+}
+
+func TestReflection(t *testing.T) {
+	examples := []struct {
+		typ      reflect.Type
+		expected reflect.Type // nil if not a Value type
+	}{
+		{reflect.TypeOf(Value[int]{}), reflect.TypeOf(0)},
+		{reflect.TypeOf(Value[[]string]{}), reflect.TypeOf([]string{})},
+		{reflect.TypeOf(&Value[int]{}), nil},
+		{reflect.TypeOf(struct{}{}), nil},
+		{reflect.TypeOf(""), nil},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.typ.String(), func(t *testing.T) {
+			actual, ok := ElemType(local.typ)
+			if ok != (local.expected != nil) || actual != local.expected {
+				t.Errorf("expected %v, got %v", local.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFrom(t *testing.T) {
+	params := struct{ Count Value[int] }{}
+	field := reflect.ValueOf(&params).Elem().Field(0)
+
+	o, ok := From(field)
+	if !ok {
+		t.Fatal("expected Optional from Value field")
+	}
+	o.Reflect().SetInt(3)
+	o.MarkSet()
+
+	if !params.Count.IsSet() || params.Count.Get() != 3 {
+		t.Errorf("expected field to be set to 3, got %v", params.Count)
+	}
+
+	// not addressable, so it's a copy
+	o, _ = From(reflect.ValueOf(params).Field(0))
+	if !o.IsSet() || o.Reflect().Int() != 3 {
+		t.Errorf("expected copy to be set to 3, got %v", o)
+	}
+
+	if _, ok := From(reflect.ValueOf(3)); ok {
+		t.Error("expected !ok for non-Value")
+	}
+
+	unexported := struct{ count Value[int] }{}
+	if _, ok := From(reflect.ValueOf(&unexported).Elem().Field(0)); ok {
+		t.Error("expected !ok for unexported field")
+	}
+	if _, ok := From(reflect.ValueOf(unexported).Field(0)); ok {
+		t.Error("expected !ok for unexported, unaddressable field")
+	}
+}
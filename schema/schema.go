@@ -0,0 +1,420 @@
+// Package schema generates JSON Schema (draft 2020-12) for a plugin's
+// settings, as written in the `settings:` of a `.drone.yml` step, so that
+// editors and linters can check them before the pipeline runs.  The schema is
+// built from the same description as the usage package: the settings' names,
+// types, descriptions (from `doc` tags), defaults, and `validate` rules.
+//
+//	s, err := schema.For(reflect.TypeOf(Params{}))
+//	out, err := json.MarshalIndent(s, "", "  ")
+//
+// Like the environment, the schema is flat: the fields of inner and embedded
+// structs are settings of their own.  Lists, maps, and any structs inside them
+// are described as arrays and objects.  Any setting may also be given as a
+// `from_secret:` reference; secret settings (`cmd:",secret"`) are marked
+// write-only, and their defaults are left out.
+//
+// For a plugin with commands, ForRegistry() (or ForCommands(), for the params
+// map given to simple.ExecCommand()) describes each command, and chooses
+// between them with `oneOf` on the `command` (and `subcommand`) settings.
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JaredReisinger/drone-plugin-helper/env"
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+	"github.com/JaredReisinger/drone-plugin-helper/simple"
+	"github.com/JaredReisinger/drone-plugin-helper/usage"
+)
+
+// Draft is the JSON Schema dialect of the generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+const fromSecretRef = "#/$defs/from_secret"
+
+// Schema is a JSON Schema, with only the keywords this package generates.
+// Marshal it with encoding/json.
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	WriteOnly   bool               `json:"writeOnly,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// AdditionalProperties describes the values of a map.
+	AdditionalProperties *Schema             `json:"additionalProperties,omitempty"`
+	DependentRequired    map[string][]string `json:"dependentRequired,omitempty"`
+	AllOf                []*Schema           `json:"allOf,omitempty"`
+	AnyOf                []*Schema           `json:"anyOf,omitempty"`
+	OneOf                []*Schema           `json:"oneOf,omitempty"`
+	Not                  *Schema             `json:"not,omitempty"`
+	Defs                 map[string]*Schema  `json:"$defs,omitempty"`
+}
+
+// For returns the schema for the params struct type (or pointer to one).
+func For(typ reflect.Type) (*Schema, error) {
+	u, err := usage.For(typ)
+	if err != nil {
+		return nil, err
+	}
+	return FromUsage(u), nil
+}
+
+// Of returns the schema for the params struct (or pointer to one), like For(),
+// with any values already set in params as the settings' defaults.
+func Of(params interface{}) (*Schema, error) {
+	u, err := usage.Of(params)
+	if err != nil {
+		return nil, err
+	}
+	return FromUsage(u), nil
+}
+
+// FromUsage returns the schema for the settings in u.  This is handy for
+// tools that build the usage themselves, like drone-plugin-docgen.
+func FromUsage(u *usage.Usage) *Schema {
+	s := object(u)
+	s.Schema = Draft
+	s.Title = u.Type.Name()
+	addDefs(s)
+	return s
+}
+
+// Commands returns the schema for a plugin with commands: one of the usages,
+// chosen by the `command` (and `subcommand`) settings.  The keys are command
+// paths, like "dependency build".
+func Commands(commands map[string]*usage.Usage) *Schema {
+	paths := make([]string, 0, len(commands))
+	for path := range commands {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	s := &Schema{Schema: Draft, Type: "object"}
+	for _, path := range paths {
+		c := object(commands[path])
+		c.Title = path
+		// the path alternatives describe these (from simple.Command) instead
+		delete(c.Properties, "command")
+		delete(c.Properties, "subcommand")
+		c.AnyOf = commandPaths(strings.Fields(path))
+		s.OneOf = append(s.OneOf, c)
+	}
+	addDefs(s)
+	return s
+}
+
+// ForRegistry returns the schema for all of the commands in the registry (see
+// Commands()), with any defaults their params are created with.
+func ForRegistry(r *simple.Registry) (*Schema, error) {
+	commands := make(map[string]*usage.Usage)
+	for _, path := range r.Commands() {
+		params, err := r.Lookup(strings.Fields(path)...)
+		if err != nil {
+			return nil, err
+		}
+		commands[path], err = usage.Of(params)
+		if err != nil {
+			return nil, fmt.Errorf("command %q: %w", path, err)
+		}
+	}
+	return Commands(commands), nil
+}
+
+// ForCommands returns the schema for the params map given to
+// simple.ExecCommand(), like ForRegistry().
+func ForCommands(paramsMap map[string]interface{}) (*Schema, error) {
+	r := &simple.Registry{}
+	for path, params := range paramsMap {
+		r.Handle(path, params)
+	}
+	return ForRegistry(r)
+}
+
+// object returns the schema for the settings in u, without the root-level
+// keywords.
+func object(u *usage.Usage) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	exclusive := make(map[string][]string)
+	together := make(map[string][]string)
+	var groups []string
+
+	for _, setting := range u.Settings {
+		s.Properties[setting.Name] = property(setting)
+		if setting.Required {
+			s.Required = append(s.Required, setting.Name)
+		}
+		for _, rule := range setting.Rules {
+			switch rule.Name {
+			case "exclusive":
+				if exclusive[rule.Arg] == nil && together[rule.Arg] == nil {
+					groups = append(groups, rule.Arg)
+				}
+				exclusive[rule.Arg] = append(exclusive[rule.Arg], setting.Name)
+			case "together":
+				if exclusive[rule.Arg] == nil && together[rule.Arg] == nil {
+					groups = append(groups, rule.Arg)
+				}
+				together[rule.Arg] = append(together[rule.Arg], setting.Name)
+			}
+		}
+	}
+
+	// at most one of an exclusive group: no two of them together
+	for _, group := range groups {
+		names := exclusive[group]
+		if len(names) < 2 {
+			continue
+		}
+		not := &Schema{}
+		for i := range names {
+			for _, other := range names[i+1:] {
+				not.AnyOf = append(not.AnyOf, &Schema{Required: []string{names[i], other}})
+			}
+		}
+		s.AllOf = append(s.AllOf, &Schema{Not: not})
+	}
+
+	// all or none of a together group: each of them requires the rest
+	for _, group := range groups {
+		names := together[group]
+		if len(names) < 2 {
+			continue
+		}
+		if s.DependentRequired == nil {
+			s.DependentRequired = make(map[string][]string)
+		}
+		for _, name := range names {
+			for _, other := range names {
+				if other != name {
+					s.DependentRequired[name] = append(s.DependentRequired[name], other)
+				}
+			}
+		}
+	}
+
+	return s
+}
+
+// property returns the schema for a single setting.  Drone can take any
+// setting from a secret, so each one may also be a `from_secret:` reference;
+// a secret setting (`cmd:",secret"`) is write-only, and has no default.
+func property(setting *usage.Setting) *Schema {
+	typ := valueType(setting.Field.StructField.Type)
+	s := typeSchema(typ)
+	addRules(s, typ, setting.Rules)
+
+	p := &Schema{
+		Description: setting.Description,
+		WriteOnly:   setting.Flag.Secret,
+		AnyOf:       []*Schema{s, {Ref: fromSecretRef}},
+	}
+	if setting.Default != "" && !setting.Flag.Secret {
+		p.Default = defaultValue(setting.Default, typ)
+	}
+	return p
+}
+
+// valueType returns the type of the value held by the type, ignoring pointers
+// and optional.Value wrappers.
+func valueType(typ reflect.Type) reflect.Type {
+	typ = fields.TypeIndirect(typ)
+	if elem, ok := optional.ElemType(typ); ok {
+		typ = fields.TypeIndirect(elem)
+	}
+	return typ
+}
+
+// typeSchema returns the schema for values of the type.
+func typeSchema(typ reflect.Type) *Schema {
+	typ = valueType(typ)
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: typeSchema(typ.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(typ.Elem())}
+	case reflect.Struct:
+		u, err := usage.For(typ)
+		if err != nil {
+			return &Schema{Type: "object"}
+		}
+		return object(u)
+	}
+	// anything goes
+	return &Schema{}
+}
+
+// addRules adds the `validate` rules to the schema.  As with the rules
+// themselves, min and max are lengths for strings and lists, and the others
+// apply to each element of a list.
+func addRules(s *Schema, typ reflect.Type, rules env.Rules) {
+	elem, elemType := s, typ
+	if s.Type == "array" {
+		elem, elemType = s.Items, valueType(typ.Elem())
+	}
+
+	for _, rule := range rules {
+		switch rule.Name {
+		case "oneof":
+			for _, option := range strings.Split(rule.Arg, "|") {
+				elem.Enum = append(elem.Enum, defaultValue(option, elemType))
+			}
+
+		case "min", "max":
+			n, err := strconv.ParseFloat(rule.Arg, 64)
+			if err != nil {
+				continue
+			}
+			length := int(n)
+			switch {
+			case s.Type == "array" && rule.Name == "min":
+				s.MinItems = &length
+			case s.Type == "array":
+				s.MaxItems = &length
+			case s.Type == "string" && rule.Name == "min":
+				s.MinLength = &length
+			case s.Type == "string":
+				s.MaxLength = &length
+			case rule.Name == "min":
+				s.Minimum = &n
+			default:
+				s.Maximum = &n
+			}
+
+		case "pattern":
+			elem.Pattern = rule.Arg
+
+		case "url":
+			elem.Format = "uri"
+		}
+	}
+}
+
+// defaultValue converts a value, as formatted by the usage package, to the
+// JSON value for the type.  Lists are comma-separated, just as Drone passes
+// them to the plugin.
+func defaultValue(value string, typ reflect.Type) interface{} {
+	switch typ.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case reflect.Slice:
+		elemType := valueType(typ.Elem())
+		values := []interface{}{}
+		for _, part := range strings.Split(value, ",") {
+			values = append(values, defaultValue(part, elemType))
+		}
+		return values
+	}
+	return value
+}
+
+// commandPaths returns the ways of giving the command path: all of it in the
+// `command` setting (as a string or a list), or split between `command` and
+// `subcommand`.  Without a subcommand, the `subcommand` setting must be
+// absent, since it would otherwise lengthen the path.
+func commandPaths(path []string) (paths []*Schema) {
+	for i := len(path); i > 0; i-- {
+		s := &Schema{
+			Properties: map[string]*Schema{"command": pathEnum(path[:i])},
+			Required:   []string{"command"},
+		}
+		if i < len(path) {
+			s.Properties["subcommand"] = pathEnum(path[i:])
+			s.Required = append(s.Required, "subcommand")
+		} else {
+			s.Not = &Schema{Required: []string{"subcommand"}}
+		}
+		paths = append(paths, s)
+	}
+	return
+}
+
+// pathEnum allows the words as a space-separated string or a list.
+func pathEnum(words []string) *Schema {
+	list := make([]interface{}, len(words))
+	for i, word := range words {
+		list[i] = word
+	}
+	return &Schema{Enum: []interface{}{strings.Join(words, " "), list}}
+}
+
+// addDefs adds the definitions that the schema refers to.
+func addDefs(s *Schema) {
+	if !refers(s, fromSecretRef) {
+		return
+	}
+	s.Defs = map[string]*Schema{
+		"from_secret": {
+			Description: "the value of a secret",
+			Type:        "object",
+			Properties:  map[string]*Schema{"from_secret": {Type: "string", Description: "the name of the secret"}},
+			Required:    []string{"from_secret"},
+		},
+	}
+}
+
+// refers reports whether the schema (or any schema inside it) refers to ref.
+func refers(s *Schema, ref string) bool {
+	if s == nil {
+		return false
+	}
+	if s.Ref == ref {
+		return true
+	}
+	for _, p := range s.Properties {
+		if refers(p, ref) {
+			return true
+		}
+	}
+	for _, list := range [][]*Schema{s.AllOf, s.AnyOf, s.OneOf} {
+		for _, sub := range list {
+			if refers(sub, ref) {
+				return true
+			}
+		}
+	}
+	return refers(s.Items, ref) || refers(s.AdditionalProperties, ref) || refers(s.Not, ref)
+}
@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+	"github.com/JaredReisinger/drone-plugin-helper/simple"
+)
+
+type schemaGlobal struct {
+	Debug bool `doc:"enable verbose output"`
+}
+
+type schemaTLS struct {
+	CertFile string `validate:"together=tls"`
+	KeyFile  string `validate:"together=tls"`
+}
+
+type schemaParams struct {
+	schemaGlobal
+	TLS       schemaTLS
+	Namespace string              `doc:"the namespace" validate:"required,min=1,max=63"`
+	Output    string              `validate:"oneof=table|json"`
+	Retries   optional.Value[int] `validate:"min=0,max=5"`
+	Workers   uint
+	Values    []string `validate:"max=3,pattern=\\.yaml$"`
+	Ports     []int    `validate:"oneof=80|443"`
+	Labels    map[string]string
+	Repo      string `validate:"url,exclusive=source"`
+	Path      string `validate:"exclusive=source"`
+	Token     string `cmd:",secret"`
+}
+
+func marshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestFor(t *testing.T) {
+	s, err := For(reflect.TypeOf(&schemaParams{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.Schema != Draft || s.Title != "schemaParams" || s.Type != "object" {
+		t.Errorf("unexpected root: %q, %q, %q", s.Schema, s.Title, s.Type)
+	}
+
+	examples := []struct {
+		name     string
+		expected string
+	}{
+		{"debug", `{"description":"enable verbose output","anyOf":[{"type":"boolean"},{"$ref":"#/$defs/from_secret"}]}`},
+		{"cert_file", `{"anyOf":[{"type":"string"},{"$ref":"#/$defs/from_secret"}]}`},
+		{"namespace", `{"description":"the namespace","anyOf":[{"type":"string","minLength":1,"maxLength":63},{"$ref":"#/$defs/from_secret"}]}`},
+		{"output", `{"anyOf":[{"type":"string","enum":["table","json"]},{"$ref":"#/$defs/from_secret"}]}`},
+		{"retries", `{"anyOf":[{"type":"integer","minimum":0,"maximum":5},{"$ref":"#/$defs/from_secret"}]}`},
+		{"workers", `{"anyOf":[{"type":"integer","minimum":0},{"$ref":"#/$defs/from_secret"}]}`},
+		{"values", `{"anyOf":[{"type":"array","items":{"type":"string","pattern":"\\.yaml$"},"maxItems":3},{"$ref":"#/$defs/from_secret"}]}`},
+		{"ports", `{"anyOf":[{"type":"array","items":{"type":"integer","enum":[80,443]}},{"$ref":"#/$defs/from_secret"}]}`},
+		{"labels", `{"anyOf":[{"type":"object","additionalProperties":{"type":"string"}},{"$ref":"#/$defs/from_secret"}]}`},
+		{"repo", `{"anyOf":[{"type":"string","format":"uri"},{"$ref":"#/$defs/from_secret"}]}`},
+		{"token", `{"writeOnly":true,"anyOf":[{"type":"string"},{"$ref":"#/$defs/from_secret"}]}`},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			p, ok := s.Properties[local.name]
+			if !ok {
+				t.Fatalf("expected property %q", local.name)
+			}
+			if actual := marshal(t, p); actual != local.expected {
+				t.Errorf("expected %q, got %q", local.expected, actual)
+			}
+		})
+	}
+
+	if expected, actual := `["namespace"]`, marshal(t, s.Required); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if expected, actual := `{"cert_file":["key_file"],"key_file":["cert_file"]}`, marshal(t, s.DependentRequired); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if expected, actual := `[{"not":{"anyOf":[{"required":["repo","path"]}]}}]`, marshal(t, s.AllOf); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if _, ok := s.Defs["from_secret"]; !ok {
+		t.Errorf("expected from_secret definition")
+	}
+}
+
+func TestOf(t *testing.T) {
+	s, err := Of(&schemaParams{
+		Output:  "json",
+		Retries: optional.Of(0),
+		Values:  []string{"a.yaml", "b.yaml"},
+		Ports:   []int{443},
+		Token:   "hunter2",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	examples := []struct {
+		name     string
+		expected string
+	}{
+		{"output", `"json"`},
+		{"retries", `0`},
+		{"values", `["a.yaml","b.yaml"]`},
+		{"ports", `[443]`},
+		{"namespace", `null`},
+		{"token", `null`},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			if actual := marshal(t, s.Properties[local.name].Default); actual != local.expected {
+				t.Errorf("expected %q, got %q", local.expected, actual)
+			}
+		})
+	}
+}
+
+type schemaInstall struct {
+	simple.Command
+	Chart string `validate:"required"`
+}
+
+type schemaStatus struct {
+	simple.Command
+	Output string `validate:"oneof=table|json"`
+}
+
+func TestForCommands(t *testing.T) {
+	s, err := ForCommands(map[string]interface{}{
+		"install":    schemaInstall{},
+		"get values": &schemaStatus{Output: "table"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(s.OneOf) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(s.OneOf))
+	}
+
+	get, install := s.OneOf[0], s.OneOf[1]
+	if get.Title != "get values" || install.Title != "install" {
+		t.Errorf("expected commands in order, got %q, %q", get.Title, install.Title)
+	}
+	if _, ok := get.Properties["command"]; ok {
+		t.Errorf("expected no command property")
+	}
+	if expected, actual := `"table"`, marshal(t, get.Properties["output"].Default); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	expected := `[{"properties":{"command":{"enum":["get values",["get","values"]]}},"required":["command"],"not":{"required":["subcommand"]}},` +
+		`{"properties":{"command":{"enum":["get",["get"]]},"subcommand":{"enum":["values",["values"]]}},"required":["command","subcommand"]}]`
+	if actual := marshal(t, get.AnyOf); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	expected = `[{"properties":{"command":{"enum":["install",["install"]]}},"required":["command"],"not":{"required":["subcommand"]}}]`
+	if actual := marshal(t, install.AnyOf); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
@@ -1,57 +1,185 @@
 package simple
 
 import (
+	"bytes"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/JaredReisinger/drone-plugin-helper/cmd"
 	"github.com/JaredReisinger/drone-plugin-helper/env"
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/usage"
 )
 
 // TODO: better name than "Exec()"?  That doesn't imply any of the parsing that
 // will occur.  Perhaps "Handoff()", or "Passthrough()"?
 
-// Exec is the all-in-one, "just wrap a command-line tool" method.  If
-// you don't need to inspect the values and simply need a one-to-one mapping
-// from Drone config through PLUGIN_ environment variables, and into the
-// command-line, this is by far the easiest way to get there.
-func Exec(command string, params interface{}) {
-	_, err := env.Parse(env.Extract(os.Environ(), "PLUGIN_"), params)
+const (
+	// EnvPrefix is the prefix Drone adds to the names of plugin settings when
+	// exposing them as environment variables.
+	EnvPrefix = env.DefaultPrefix
+)
+
+var (
+	// output is where usage is written; swapped out by tests.
+	output io.Writer = os.Stdout
+)
+
+// settings are the helper's own settings, which control the plugin itself
+// rather than being passed along to the underlying tool.  They are named with a
+// "plugin_" prefix so that they don't collide with the tool's own options
+// (`helm` has its own `--dry-run`, for example).
+type settings struct {
+	// PluginDryRun shows the command (and environment) that would be run,
+	// without actually running it.
+	PluginDryRun bool `doc:"show the command that would be run, without running it"`
+
+	// PluginHelp shows the usage (see the usage package) instead of running
+	// the command.
+	PluginHelp bool `doc:"show this help, without running the command"`
+}
+
+// helpSetting is the unprefixed `help` setting, which is only used for usage
+// when the params don't have a Help setting of their own (which would be
+// passed along to the tool, like `helm --help`).
+type helpSetting struct {
+	Help bool
+}
+
+// parseSettings parses the helper's own settings.
+func parseSettings(vars map[string]string, params interface{}) (s *settings, err error) {
+	s = &settings{}
+	_, err = env.Parse(vars, s)
+	if err != nil || s.PluginHelp {
+		return
+	}
+
+	plan, err := fields.For(reflect.TypeOf(params))
+	if err != nil {
+		return
+	}
+	if _, ok := plan.Field("Help"); !ok {
+		h := &helpSetting{}
+		_, err = env.Parse(vars, h)
+		s.PluginHelp = h.Help
+	}
+	return
+}
+
+// writeUsage writes the usage for the params, followed by the helper's own
+// settings.
+func writeUsage(w io.Writer, title string, params interface{}) error {
+	u, err := usage.Of(params)
+	if err != nil {
+		return err
+	}
+	err = u.Write(w, fmt.Sprintf("Settings for %s:", title))
 	if err != nil {
-		log.Fatalf("error parsing environment: %+v\n", err)
+		return err
 	}
 
-	cmd.Exec(command, params)
+	plugin, err := usage.For(reflect.TypeOf(settings{}))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, "\nPlugin settings:\n\n")
+	for _, s := range plugin.Settings {
+		if err == nil {
+			_, err = fmt.Fprintf(w, "  %-16s%s\n", s.Name, s.Description)
+		}
+	}
+	return err
 }
 
-// Command is minimal param data needed to choose command-specific parameters.
-// For convenience, it can also be used as the first embedded field in any
-// command-specific parameter struct definitions.  It assumes that the command
-// is indicated via the PLUGIN_COMMAND environment variable, and any subcommand
-// via PLUGIN_SUBCOMMAND.
-type Command struct {
-	Command    string `cmd:",positional"`
-	Subcommand string `cmd:",positional"`
+// Exec is the all-in-one, "just wrap a command-line tool" method.  If
+// you don't need to inspect the values and simply need a one-to-one mapping
+// from Drone config through PLUGIN_ environment variables, and into the
+// command-line, this is by far the easiest way to get there.  Any errors exit
+// the process; see Execute() for a variant that returns them instead.
+//
+// Because the environment is parsed with env.ParseEnviron(), a setting
+// that is explicitly set to a "zero value" (like `retries: 0`) is still passed
+// along to the command-line, even if the field is not a pointer.  An empty
+// setting (like `namespace:`) is not a value at all, and is left out (see
+// env.Empty).
+func Exec(command string, params interface{}, opts ...cmd.Option) {
+	cmd.Main(func() error {
+		return Execute(command, params, opts...)
+	})
 }
 
-// ExecCommand is the all-in-one method for tools which have subcommands,
-// like `git` or `helm`.
-func ExecCommand(command string, paramsMap map[string]interface{}) {
-	commandParams := &Command{}
-	_, err := env.Parse(env.Extract(os.Environ(), "PLUGIN_"), commandParams)
+// Execute is like Exec(), but returns any error instead of exiting the
+// process.  If the command itself fails, the error is a *cmd.ExitError.  If
+// the `plugin_help` setting is true (or `help`, unless the params have a Help
+// setting of their own), the usage for the params is shown instead of running
+// the command.  The usage is also shown when the environment can't be parsed.
+func Execute(command string, params interface{}, opts ...cmd.Option) error {
+	return execute(command, nil, params, nil, opts...)
+}
+
+// execute parses the environment into params, calls prepare (if any), and
+// runs the command.  The path is only used to title the usage.
+func execute(command string, path []string, params interface{}, prepare func() error, opts ...cmd.Option) error {
+	vars := env.Extract(os.Environ(), EnvPrefix)
+	title := strings.Join(append([]string{command}, path...), " ")
+
+	s, err := parseSettings(vars, params)
 	if err != nil {
-		log.Fatalf("error parsing environment: %+v\n", err)
+		return fmt.Errorf("error parsing environment: %w", err)
 	}
-	key := commandParams.Command
-	if commandParams.Subcommand != "" {
-		key = fmt.Sprintf("%s %s", commandParams.Command, commandParams.Subcommand)
+	if s.PluginHelp {
+		return writeUsage(output, title, params)
 	}
 
-	params, ok := paramsMap[key]
-	if !ok {
-		log.Fatalf("command %q not recognized\n", key)
+	// the usage is written before parsing, since a failed parse leaves params
+	// partly filled in, and the given settings would show up as defaults
+	var help bytes.Buffer
+	writeUsage(&help, title, params)
+
+	_, md, err := env.ParseEnviron(os.Environ(), EnvPrefix, params)
+	if err != nil {
+		// show what *is* accepted, to help fix the settings
+		output.Write(help.Bytes())
+		return fmt.Errorf("error parsing environment: %w", err)
+	}
+	if prepare != nil {
+		err = prepare()
+		if err != nil {
+			return err
+		}
 	}
+	// prepended, so that an explicit WithMetadata() option still wins
+	opts = append([]cmd.Option{cmd.WithMetadata(md)}, opts...)
+
+	if s.PluginDryRun {
+		// prepended, so that an explicit DryRun() option still wins
+		opts = append([]cmd.Option{cmd.DryRun(os.Stdout)}, opts...)
+	}
+
+	return cmd.Execute(command, params, opts...)
+}
 
-	Exec(command, params)
+// ExecCommand is the all-in-one method for tools which have subcommands,
+// like `git` or `helm`.  The keys of paramsMap are command paths, like
+// "dependency build"; see Registry for the details, and Register() for a
+// type-safe alternative.  Any errors exit the
+// process; see ExecuteCommand() for a variant that returns them instead.
+func ExecCommand(command string, paramsMap map[string]interface{}, opts ...cmd.Option) {
+	cmd.Main(func() error {
+		return ExecuteCommand(command, paramsMap, opts...)
+	})
+}
+
+// ExecuteCommand is like ExecCommand(), but returns any error instead of
+// exiting the process.  The params in the map are used as templates, so each
+// run gets a new copy; see Registry.Handle().
+func ExecuteCommand(command string, paramsMap map[string]interface{}, opts ...cmd.Option) error {
+	r := &Registry{}
+	for path, params := range paramsMap {
+		r.Handle(path, params)
+	}
+	return r.Execute(command, opts...)
 }
@@ -0,0 +1,187 @@
+package simple
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/cmd"
+	"github.com/JaredReisinger/drone-plugin-helper/env"
+)
+
+type testParams struct {
+	Command
+	Purge       bool
+	ReleaseName string `cmd:",positional"`
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
+
+func TestExecuteDryRun(t *testing.T) {
+	t.Setenv("PLUGIN_COMMAND", "delete")
+	t.Setenv("PLUGIN_PURGE", "true")
+	t.Setenv("PLUGIN_RELEASE_NAME", "my release")
+
+	var b strings.Builder
+	err := Execute("helm", &testParams{}, cmd.DryRun(&b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "helm delete --purge 'my release'"
+	if actual := lastLine(b.String()); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestExecuteDryRunSetting(t *testing.T) {
+	t.Setenv("PLUGIN_PLUGIN_DRY_RUN", "true")
+
+	// "false" would fail if it were actually run
+	err := Execute("false", &testParams{})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExecuteExplicitZero(t *testing.T) {
+	t.Setenv("PLUGIN_RETRIES", "0")
+
+	params := &struct{ Retries, Timeout int }{}
+
+	var b strings.Builder
+	err := Execute("curl", params, cmd.DryRun(&b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "curl --retries 0"
+	if actual := lastLine(b.String()); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestExecuteEmptySetting(t *testing.T) {
+	t.Setenv("PLUGIN_NAMESPACE", "")
+	t.Setenv("PLUGIN_RETRIES", "")
+
+	params := &struct {
+		Namespace string
+		Retries   int
+	}{}
+
+	var b strings.Builder
+	err := Execute("helm", params, cmd.DryRun(&b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "helm"
+	if actual := lastLine(b.String()); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+// captureOutput swaps out the usage output for the duration of the test.
+func captureOutput(t *testing.T) *strings.Builder {
+	var b strings.Builder
+	saved := output
+	output = &b
+	t.Cleanup(func() { output = saved })
+	return &b
+}
+
+type helpParams struct {
+	Namespace string `doc:"the namespace to use" validate:"required"`
+	Retries   int    `validate:"min=0"`
+}
+
+func TestExecuteHelp(t *testing.T) {
+	examples := []struct {
+		name    string
+		setting string
+	}{
+		{"plugin_help", "PLUGIN_PLUGIN_HELP"},
+		{"help", "PLUGIN_HELP"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			t.Setenv(local.setting, "true")
+			b := captureOutput(t)
+
+			// "false" would fail if it were actually run, and the missing
+			// (required) namespace would fail the parsing
+			err := Execute("false", &helpParams{Retries: 3})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, expected := range []string{
+				"Settings for false:",
+				"  namespace  string   yes                --namespace  the namespace to use",
+				"  retries    integer            3        --retries    (at least 0)",
+				"  plugin_help     show this help, without running the command",
+			} {
+				if !strings.Contains(b.String(), expected+"\n") {
+					t.Errorf("expected %q in usage:\n%s", expected, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteHelpPassedThrough(t *testing.T) {
+	t.Setenv("PLUGIN_HELP", "true")
+	b := captureOutput(t)
+
+	params := &struct{ Help bool }{}
+	var dryRun strings.Builder
+	err := Execute("helm", params, cmd.DryRun(&dryRun))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "helm --help"
+	if actual := lastLine(dryRun.String()); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if b.Len() != 0 {
+		t.Errorf("unexpected usage: %s", b.String())
+	}
+}
+
+func TestExecuteParseErrorUsage(t *testing.T) {
+	t.Setenv("PLUGIN_RETRIES", "abc")
+	b := captureOutput(t)
+
+	err := Execute("false", &helpParams{})
+	var fieldErr *env.ParseTypeError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *env.ParseTypeError, got %v", err)
+	}
+	if expected := "PLUGIN_RETRIES"; fieldErr.Var != expected {
+		t.Errorf("expected %q, got %q", expected, fieldErr.Var)
+	}
+	if !strings.HasPrefix(b.String(), "Settings for false:\n") {
+		t.Errorf("expected usage, got %q", b.String())
+	}
+}
+
+func TestExecuteParseErrorUsageDefaults(t *testing.T) {
+	t.Setenv("PLUGIN_NAMESPACE", "secret-host.example")
+	t.Setenv("PLUGIN_RETRIES", "abc")
+	b := captureOutput(t)
+
+	err := Execute("false", &helpParams{})
+	if err == nil {
+		t.Fatal("missing expected error")
+	}
+	if strings.Contains(b.String(), "secret-host.example") {
+		t.Errorf("expected the given settings to be left out of the usage, got %q", b.String())
+	}
+}
@@ -0,0 +1,364 @@
+package simple
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/JaredReisinger/drone-plugin-helper/cmd"
+	"github.com/JaredReisinger/drone-plugin-helper/env"
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+)
+
+// Command is minimal param data needed to choose command-specific parameters.
+// For convenience, it can also be used as the first embedded field in any
+// command-specific parameter struct definitions.  The command path is taken
+// from the PLUGIN_COMMAND setting, which can be a list (`command: [s3, cp]`)
+// or a space-separated string (`command: s3 cp`), followed by the
+// PLUGIN_SUBCOMMAND setting, which works the same way.  When a params struct
+// embeds Command, the resolved path is written back to it, so that the
+// command-line always starts with the path as registered.
+type Command struct {
+	Command    []string `cmd:",positional"`
+	Subcommand []string `cmd:",positional"`
+}
+
+// Path returns the command path: the words of the command, followed by those
+// of the subcommand.
+func (c *Command) Path() (path []string) {
+	for _, setting := range [][]string{c.Command, c.Subcommand} {
+		for _, part := range setting {
+			path = append(path, strings.Fields(part)...)
+		}
+	}
+	return
+}
+
+// setPath replaces the command (and subcommand) with the resolved path.
+func (c *Command) setPath(path []string) {
+	c.Command = path
+	c.Subcommand = nil
+}
+
+// pathSetter is satisfied by any params struct that embeds Command.
+type pathSetter interface {
+	setPath(path []string)
+}
+
+// UnknownCommandError is returned when the command path doesn't match any
+// registered command.
+type UnknownCommandError struct {
+	Path      []string // the requested command path
+	Available []string // the registered commands closest to the path
+}
+
+func (e *UnknownCommandError) Error() string {
+	msg := fmt.Sprintf("command %q not recognized", strings.Join(e.Path, " "))
+	if len(e.Path) == 0 {
+		msg = "no command given"
+	}
+	if len(e.Available) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s (available commands: %s)", msg, strings.Join(e.Available, ", "))
+}
+
+// Registry is a routing tree of commands, for tools with subcommands of any
+// depth, like `aws s3 cp` or `gcloud container clusters get-credentials`.
+// Each command is registered with its full path and its params type, using
+// Register() (or one of the Handle methods).  Every run gets a new params
+// value, and every params type is checked when it is registered, so that a
+// params struct the helpers can't use is found at startup, rather than when
+// that one command is run.  The zero Registry is empty and ready to use.
+type Registry struct {
+	root route
+}
+
+// route is a node in the routing tree.  A node may have both a command (for
+// its own path) and children (for longer paths), like `helm get` and
+// `helm get values`.
+type route struct {
+	entry    *entry
+	children map[string]*route
+}
+
+// entry is a registered command: how to create its params, and its hooks.
+type entry struct {
+	newParams func() interface{} // returns a new pointer to the params struct
+	validate  func(params interface{}) error
+	before    func(params interface{}) error
+	after     func(params interface{}, err error) error
+}
+
+// Hook adds an optional function to a command registered with Register() or
+// RegisterFunc().
+type Hook[T any] func(*hooks[T])
+
+type hooks[T any] struct {
+	validate func(params *T) error
+	before   func(params *T) error
+	after    func(params *T, err error) error
+}
+
+// Validate checks the params once the environment has been parsed into them
+// (and any `validate` tags have been checked); an error stops the command
+// from being run.
+func Validate[T any](fn func(params *T) error) Hook[T] {
+	return func(h *hooks[T]) {
+		h.validate = fn
+	}
+}
+
+// Before is called after Validate, just before the command-line is created,
+// and can adjust the params (or stop the command by returning an error).
+func Before[T any](fn func(params *T) error) Hook[T] {
+	return func(h *hooks[T]) {
+		h.before = fn
+	}
+}
+
+// After is called once the command has run, or failed to, with the error (if
+// any) from parsing, the other hooks, or the command itself.  Whatever it
+// returns is the result of the run, so it can translate, wrap, or suppress
+// the error.
+func After[T any](fn func(params *T, err error) error) Hook[T] {
+	return func(h *hooks[T]) {
+		h.after = fn
+	}
+}
+
+// Register registers the params struct type T for the command path, whose
+// words are separated by spaces, like "dependency build".  Each run gets a new
+// (zero) T.  It panics if the path is empty or already registered, or if T
+// can't be used as params (see env.CheckType() and cmd.Validate()), since
+// these are programming errors.
+func Register[T any](r *Registry, path string, opts ...Hook[T]) {
+	RegisterFunc(r, path, func() *T { return new(T) }, opts...)
+}
+
+// RegisterFunc is like Register(), but each run gets its params from
+// newParams, which can fill in any defaults.
+func RegisterFunc[T any](r *Registry, path string, newParams func() *T, opts ...Hook[T]) {
+	h := &hooks[T]{}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	e := &entry{newParams: func() interface{} { return newParams() }}
+	if h.validate != nil {
+		e.validate = func(params interface{}) error { return h.validate(params.(*T)) }
+	}
+	if h.before != nil {
+		e.before = func(params interface{}) error { return h.before(params.(*T)) }
+	}
+	if h.after != nil {
+		e.after = func(params interface{}, err error) error { return h.after(params.(*T), err) }
+	}
+
+	r.add(path, reflect.TypeOf((*T)(nil)).Elem(), e)
+}
+
+// Handle registers params for the command path, like Register().  The params
+// can be a struct or a pointer to one, and are used as a template: each run
+// gets a new copy of them.  (The copy is shallow, so any slices or pointers
+// are shared between runs.)
+func (r *Registry) Handle(path string, params interface{}) {
+	val := reflect.ValueOf(params)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			panic(fmt.Sprintf("simple: nil params for command %q", path))
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		panic(fmt.Sprintf("simple: nil params for command %q", path))
+	}
+
+	r.add(path, val.Type(), &entry{newParams: func() interface{} {
+		params := reflect.New(val.Type())
+		params.Elem().Set(val)
+		return params.Interface()
+	}})
+}
+
+// HandleType registers the params type for the command path, like
+// Register().  The type can be a struct or a pointer to one; each run gets a
+// new (zero) value.
+func (r *Registry) HandleType(path string, typ reflect.Type) {
+	if typ == nil {
+		panic(fmt.Sprintf("simple: nil params type for command %q", path))
+	}
+	typ = fields.TypeIndirect(typ)
+	r.add(path, typ, &entry{newParams: func() interface{} {
+		return reflect.New(typ).Interface()
+	}})
+}
+
+// add checks the params type, and adds the command to the tree.
+func (r *Registry) add(path string, typ reflect.Type, e *entry) {
+	words := strings.Fields(path)
+	if len(words) == 0 {
+		panic("simple: empty command path")
+	}
+	if err := checkParams(typ); err != nil {
+		panic(fmt.Sprintf("simple: invalid params for command %q: %v", path, err))
+	}
+
+	node := &r.root
+	for _, word := range words {
+		if node.children == nil {
+			node.children = make(map[string]*route)
+		}
+		child, ok := node.children[word]
+		if !ok {
+			child = &route{}
+			node.children[word] = child
+		}
+		node = child
+	}
+
+	if node.entry != nil {
+		panic(fmt.Sprintf("simple: command %q registered twice", strings.Join(words, " ")))
+	}
+	node.entry = e
+}
+
+// checkParams checks that the type is a struct that both the environment can
+// be parsed into, and a command-line created from.
+func checkParams(typ reflect.Type) error {
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("expected struct type, got %s", typ)
+	}
+	if err := env.CheckType(typ); err != nil {
+		return err
+	}
+	return cmd.Validate(typ)
+}
+
+// Lookup returns new params for the command path.  If there is no such
+// command, the error is an *UnknownCommandError listing the commands under
+// the longest part of the path that did match.
+func (r *Registry) Lookup(path ...string) (params interface{}, err error) {
+	e, err := r.lookup(path)
+	if err != nil {
+		return
+	}
+	params = e.newParams()
+	return
+}
+
+func (r *Registry) lookup(path []string) (e *entry, err error) {
+	node := &r.root
+	matched := 0
+	for _, word := range path {
+		child, ok := node.children[word]
+		if !ok {
+			break
+		}
+		node = child
+		matched++
+	}
+
+	if matched == len(path) && node.entry != nil {
+		e = node.entry
+		return
+	}
+
+	err = &UnknownCommandError{Path: path, Available: node.commands(path[:matched])}
+	return
+}
+
+// Commands returns all of the registered command paths, sorted.
+func (r *Registry) Commands() []string {
+	return r.root.commands(nil)
+}
+
+// commands returns the command paths at and below the node, whose path is
+// prefix.
+func (n *route) commands(prefix []string) (commands []string) {
+	if n.entry != nil {
+		commands = append(commands, strings.Join(prefix, " "))
+	}
+	for word, child := range n.children {
+		path := append(append([]string{}, prefix...), word)
+		commands = append(commands, child.commands(path)...)
+	}
+	sort.Strings(commands)
+	return
+}
+
+// WriteUsage writes the usage for the command path (see the usage package), or
+// if there's no such command, the list of commands under the longest part of
+// the path that does match.
+func (r *Registry) WriteUsage(w io.Writer, command string, path ...string) error {
+	e, err := r.lookup(path)
+	if err == nil {
+		title := strings.Join(append([]string{command}, path...), " ")
+		return writeUsage(w, title, e.newParams())
+	}
+
+	commands := err.(*UnknownCommandError).Available
+	_, err = fmt.Fprintf(w, "Commands for %s:\n\n", command)
+	for _, c := range commands {
+		if err == nil {
+			_, err = fmt.Fprintf(w, "  %s\n", c)
+		}
+	}
+	if err == nil {
+		_, err = fmt.Fprintln(w, "\nSet `command` to choose one, and `plugin_help: true` to see its settings.")
+	}
+	return err
+}
+
+// Exec chooses the params for the command path from the environment (see
+// Command), and runs the command with them, like simple.Exec().  Any errors
+// exit the process; see Execute() for a variant that returns them instead.
+func (r *Registry) Exec(command string, opts ...cmd.Option) {
+	cmd.Main(func() error {
+		return r.Execute(command, opts...)
+	})
+}
+
+// Execute is like Exec(), but returns any error instead of exiting the
+// process.  An unknown command path is reported as an *UnknownCommandError.
+func (r *Registry) Execute(command string, opts ...cmd.Option) error {
+	vars := env.Extract(os.Environ(), EnvPrefix)
+	commandParams := &Command{}
+	_, _, err := env.ParseEnviron(os.Environ(), EnvPrefix, commandParams)
+	if err != nil {
+		return fmt.Errorf("error parsing environment: %w", err)
+	}
+
+	path := commandParams.Path()
+	e, err := r.lookup(path)
+	if err != nil {
+		if help, _ := parseSettings(vars, struct{}{}); help != nil && help.PluginHelp {
+			return r.WriteUsage(output, command, path...)
+		}
+		return err
+	}
+
+	params := e.newParams()
+	err = execute(command, path, params, func() error {
+		if setter, ok := params.(pathSetter); ok {
+			setter.setPath(path)
+		}
+		if e.validate != nil {
+			if err := e.validate(params); err != nil {
+				return err
+			}
+		}
+		if e.before != nil {
+			return e.before(params)
+		}
+		return nil
+	}, opts...)
+
+	if e.after != nil {
+		err = e.after(params, err)
+	}
+	return err
+}
@@ -0,0 +1,298 @@
+package simple
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/cmd"
+)
+
+type copyParams struct {
+	Command
+	Recursive bool
+	Source    string `cmd:",positional"`
+	Target    string `cmd:",positional"`
+}
+
+type lsParams struct {
+	Command
+	Summarize bool
+}
+
+func testRegistry() *Registry {
+	r := &Registry{}
+	r.Handle("s3 cp", &copyParams{})
+	r.Handle("s3 ls", &lsParams{})
+	r.Handle("configure list", &lsParams{})
+	r.Handle("configure", &lsParams{})
+	return r
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := testRegistry()
+
+	examples := []struct {
+		path      []string
+		available []string // nil when the lookup should succeed
+	}{
+		{[]string{"s3", "cp"}, nil},
+		{[]string{"configure"}, nil},
+		{[]string{"configure", "list"}, nil},
+		{[]string{"s3"}, []string{"s3 cp", "s3 ls"}},
+		{[]string{"s3", "mv"}, []string{"s3 cp", "s3 ls"}},
+		{[]string{"s3", "cp", "extra"}, []string{"s3 cp"}},
+		{[]string{"ec2"}, []string{"configure", "configure list", "s3 cp", "s3 ls"}},
+		{nil, []string{"configure", "configure list", "s3 cp", "s3 ls"}},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(strings.Join(local.path, " "), func(t *testing.T) {
+			params, err := r.Lookup(local.path...)
+			if local.available == nil {
+				if err != nil || params == nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var unknown *UnknownCommandError
+			if !errors.As(err, &unknown) {
+				t.Fatalf("expected *UnknownCommandError, got %v", err)
+			}
+			if !reflect.DeepEqual(unknown.Available, local.available) {
+				t.Errorf("expected %q, got %q", local.available, unknown.Available)
+			}
+		})
+	}
+}
+
+func TestUnknownCommandError(t *testing.T) {
+	_, err := testRegistry().Lookup("s3", "mv")
+	expected := `command "s3 mv" not recognized (available commands: s3 cp, s3 ls)`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+
+	_, err = (&Registry{}).Lookup()
+	expected = "no command given"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestRegistryHandlePanics(t *testing.T) {
+	examples := []struct {
+		name   string
+		path   string
+		params interface{}
+	}{
+		{"empty", " ", &lsParams{}},
+		{"nil", "s3 rm", nil},
+		{"duplicate", "s3  cp", &copyParams{}},
+		{"not a struct", "s3 rm", new(int)},
+		{"conflict", "s3 rm", &struct {
+			Force bool
+			Other bool `cmd:"--force"`
+		}{}},
+		{"invalid tag", "s3 rm", &struct {
+			Force bool `validate:"bogus"`
+		}{}},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			testRegistry().Handle(local.path, local.params)
+		})
+	}
+}
+
+func TestRegistryExecute(t *testing.T) {
+	examples := []struct {
+		command    string
+		subcommand string
+		expected   string
+	}{
+		{"s3,cp", "", "aws s3 cp --recursive a b"},
+		{"s3 cp", "", "aws s3 cp --recursive a b"},
+		{"s3", "cp", "aws s3 cp --recursive a b"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.command+"/"+local.subcommand, func(t *testing.T) {
+			t.Setenv("PLUGIN_COMMAND", local.command)
+			t.Setenv("PLUGIN_SUBCOMMAND", local.subcommand)
+			t.Setenv("PLUGIN_RECURSIVE", "true")
+			t.Setenv("PLUGIN_SOURCE", "a")
+			t.Setenv("PLUGIN_TARGET", "b")
+
+			var b strings.Builder
+			err := testRegistry().Execute("aws", cmd.DryRun(&b))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := lastLine(b.String()); actual != local.expected {
+				t.Errorf("expected %q, got %q", local.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRegisterHooks(t *testing.T) {
+	t.Setenv("PLUGIN_COMMAND", "s3 cp")
+	t.Setenv("PLUGIN_SOURCE", "a")
+
+	var calls []string
+	var seen []*copyParams
+	r := &Registry{}
+	Register(r, "s3 cp",
+		Validate(func(p *copyParams) error {
+			calls = append(calls, "validate")
+			seen = append(seen, p)
+			return nil
+		}),
+		Before(func(p *copyParams) error {
+			calls = append(calls, "before")
+			p.Target = "b"
+			return nil
+		}),
+		After(func(p *copyParams, err error) error {
+			calls = append(calls, "after")
+			return err
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		var b strings.Builder
+		err := r.Execute("aws", cmd.DryRun(&b))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "aws s3 cp a b"
+		if actual := lastLine(b.String()); actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	expected := []string{"validate", "before", "after", "validate", "before", "after"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %q, got %q", expected, calls)
+	}
+	if len(seen) != 2 || seen[0] == seen[1] {
+		t.Error("expected a new params value for each run")
+	}
+}
+
+func TestRegisterHookErrors(t *testing.T) {
+	t.Setenv("PLUGIN_COMMAND", "s3 cp")
+
+	failure := errors.New("missing source")
+	var afterErr error
+	r := &Registry{}
+	Register(r, "s3 cp",
+		Validate(func(p *copyParams) error {
+			if p.Source == "" {
+				return failure
+			}
+			return nil
+		}),
+		Before(func(p *copyParams) error {
+			t.Error("unexpected call to Before")
+			return nil
+		}),
+		After(func(p *copyParams, err error) error {
+			afterErr = err
+			return fmt.Errorf("wrapped: %w", err)
+		}),
+	)
+
+	err := r.Execute("aws", cmd.DryRun(io.Discard))
+	if !errors.Is(err, failure) || !errors.Is(afterErr, failure) {
+		t.Errorf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestRegisterFuncDefaults(t *testing.T) {
+	t.Setenv("PLUGIN_COMMAND", "s3 ls")
+
+	r := &Registry{}
+	RegisterFunc(r, "s3 ls", func() *lsParams {
+		return &lsParams{Summarize: true}
+	})
+
+	var b strings.Builder
+	err := r.Execute("aws", cmd.DryRun(&b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "aws s3 ls --summarize"
+	if actual := lastLine(b.String()); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestHandleTemplate(t *testing.T) {
+	template := &lsParams{Summarize: true}
+	r := &Registry{}
+	r.Handle("s3 ls", template)
+	r.Handle("ls", lsParams{})
+	r.HandleType("configure", reflect.TypeOf(lsParams{}))
+
+	params, err := r.Lookup("s3", "ls")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if params == template || !params.(*lsParams).Summarize {
+		t.Errorf("expected a copy of the template, got %+v", params)
+	}
+
+	for _, path := range [][]string{{"ls"}, {"configure"}} {
+		params, err := r.Lookup(path...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := params.(*lsParams); !ok {
+			t.Errorf("expected *lsParams, got %T", params)
+		}
+	}
+}
+
+func TestRegistryHelp(t *testing.T) {
+	t.Setenv("PLUGIN_PLUGIN_HELP", "true")
+
+	examples := []struct {
+		command  string
+		expected string
+	}{
+		{"", "Commands for aws:\n\n  configure\n  configure list\n  s3 cp\n  s3 ls\n"},
+		{"s3", "Commands for aws:\n\n  s3 cp\n  s3 ls\n"},
+		{"s3 cp", "Settings for aws s3 cp:\n"},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.command, func(t *testing.T) {
+			t.Setenv("PLUGIN_COMMAND", local.command)
+			b := captureOutput(t)
+
+			err := testRegistry().Execute("aws")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(b.String(), local.expected) {
+				t.Errorf("expected usage starting with %q, got:\n%s", local.expected, b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package usage describes the settings a plugin accepts, built from its params
+// struct: each setting's name (as written in `.drone.yml`), type, default,
+// whether it's required, its description, and the command-line flag it maps
+// to.  Descriptions come from `doc` tags:
+//
+//	type Params struct {
+//		Namespace string `doc:"the namespace to install into" validate:"required"`
+//	}
+//
+// The simple package shows this usage when a plugin is run with
+// `plugin_help: true`, and tools like drone-plugin-docgen build on it.
+package usage
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/JaredReisinger/drone-plugin-helper/cmd"
+	"github.com/JaredReisinger/drone-plugin-helper/env"
+	"github.com/JaredReisinger/drone-plugin-helper/fields"
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+const docTagName = "doc"
+
+// Setting describes a single plugin setting.
+type Setting struct {
+	Name        string        // the setting, as written in `.drone.yml`, like "tiller_namespace"
+	Path        string        // Go path of the field, like "GlobalParams.TillerNamespace"
+	Type        string        // a description of the type, like "integer" or "list of string"
+	Default     string        // the default value, if any (see Of())
+	Required    bool          // the field has a `required` validation rule
+	Description string        // from the field's `doc` tag
+	Rules       env.Rules     // the field's validation rules
+	Flag        cmd.FlagInfo  // how the setting appears on the command-line
+	Field       *fields.Field // the field itself
+}
+
+// Usage describes all of the settings for a params struct, in field order.
+type Usage struct {
+	Type     reflect.Type
+	Settings []*Setting
+}
+
+// For describes the settings for the params struct type (or pointer to one).
+// There are no defaults, since there are no values; see Of().
+func For(typ reflect.Type) (*Usage, error) {
+	return describe(typ, reflect.Value{})
+}
+
+// Of describes the settings for the params struct (or pointer to one), like
+// For(), and also reports any values that are already set in params (like
+// those filled in by a constructor) as the settings' defaults.  Secret values
+// are masked.
+func Of(params interface{}) (*Usage, error) {
+	val := fields.Indirect(reflect.ValueOf(params))
+	if !val.IsValid() {
+		return nil, fmt.Errorf("expected struct, got %T", params)
+	}
+	return describe(val.Type(), val)
+}
+
+func describe(typ reflect.Type, val reflect.Value) (u *Usage, err error) {
+	typ = fields.TypeIndirect(typ)
+	if typ.Kind() != reflect.Struct {
+		err = fmt.Errorf("expected struct type, got %s", typ)
+		return
+	}
+
+	plan, err := fields.For(typ)
+	if err != nil {
+		return
+	}
+	infos, err := cmd.Describe(typ)
+	if err != nil {
+		return
+	}
+	flags := make(map[string]cmd.FlagInfo)
+	for _, info := range infos {
+		flags[info.Path] = info
+	}
+
+	u = &Usage{Type: typ}
+	for _, f := range plan.Fields {
+		s := &Setting{
+			Name:        env.SettingName(f),
+			Path:        f.Path,
+			Type:        typeName(f.StructField.Type),
+			Description: f.StructField.Tag.Get(docTagName),
+			Flag:        flags[f.Path],
+			Field:       f,
+		}
+
+		if tag, ok := f.StructField.Tag.Lookup("validate"); ok {
+			s.Rules, err = env.ParseRules(tag)
+			if err != nil {
+				err = fmt.Errorf("invalid validate tag on %s: %w", f.Path, err)
+				return
+			}
+			_, s.Required = s.Rules.Get("required")
+		}
+
+		if val.IsValid() {
+			s.Default = defaultValue(f, val, s.Flag.Secret)
+		}
+
+		u.Settings = append(u.Settings, s)
+	}
+
+	return
+}
+
+// typeName describes the type in user terms, ignoring pointers and
+// optional.Value wrappers.
+func typeName(typ reflect.Type) string {
+	typ = fields.TypeIndirect(typ)
+	if elem, ok := optional.ElemType(typ); ok {
+		typ = fields.TypeIndirect(elem)
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "non-negative integer"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		return "list of " + typeName(typ.Elem())
+	}
+	return typ.String()
+}
+
+// defaultValue formats the field's value in root, if it has one.  Like
+// cmd.Create(), a "zero value" only counts if it is held by a pointer or a set
+// optional.Value.
+func defaultValue(f *fields.Field, root reflect.Value, secret bool) string {
+	val, ok := f.Value(root)
+	explicit := false
+	for ok {
+		if o, isOptional := optional.From(val); isOptional {
+			if !o.IsSet() {
+				return ""
+			}
+			val = o.Reflect()
+			explicit = true
+			continue
+		}
+		if val.Kind() != reflect.Ptr {
+			break
+		}
+		if val.IsNil() {
+			return ""
+		}
+		val = val.Elem()
+		explicit = true
+	}
+	if !ok || !val.CanInterface() || (!explicit && val.IsZero()) {
+		return ""
+	}
+	if secret {
+		return cmd.Mask
+	}
+
+	if val.Kind() == reflect.Slice {
+		parts := make([]string, val.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(val.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(val.Interface())
+}
+
+// Constraints describes the setting's validation rules (other than
+// `required`), like "one of table, json, yaml".
+func (s *Setting) Constraints() (constraints []string) {
+	for _, rule := range s.Rules {
+		switch rule.Name {
+		case "oneof":
+			constraints = append(constraints, "one of "+strings.ReplaceAll(rule.Arg, "|", ", "))
+		case "min":
+			constraints = append(constraints, "at least "+rule.Arg)
+		case "max":
+			constraints = append(constraints, "at most "+rule.Arg)
+		case "pattern":
+			constraints = append(constraints, "matching "+rule.Arg)
+		case "file":
+			constraints = append(constraints, "an existing file")
+		case "dir":
+			constraints = append(constraints, "an existing directory")
+		case "url":
+			constraints = append(constraints, "an absolute URL")
+		case "exclusive":
+			constraints = append(constraints, fmt.Sprintf("not with other %q settings", rule.Arg))
+		case "together":
+			constraints = append(constraints, fmt.Sprintf("with all other %q settings", rule.Arg))
+		}
+	}
+	return
+}
+
+// FlagText describes how the setting appears on the command-line, like
+// "--wait/--no-wait" or "(positional)".
+func (s *Setting) FlagText() string {
+	switch {
+	case s.Flag.Omit:
+		return "(not passed)"
+	case s.Flag.Positional:
+		return "(positional)"
+	case s.Flag.Extra:
+		return "(extra arguments)"
+	case s.Flag.Negated != "":
+		return s.Flag.Flag + "/" + s.Flag.Negated
+	}
+	return s.Flag.Flag
+}
+
+// Write writes the usage as a plain-text table (suitable for a build log),
+// under the given title.
+func (u *Usage) Write(w io.Writer, title string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\n\n", title)
+	if len(u.Settings) == 0 {
+		fmt.Fprintln(tw, "  (no settings)")
+		return tw.Flush()
+	}
+
+	fmt.Fprintln(tw, "  SETTING\tTYPE\tREQUIRED\tDEFAULT\tFLAG\tDESCRIPTION")
+	for _, s := range u.Settings {
+		required := ""
+		if s.Required {
+			required = "yes"
+		}
+		description := s.Description
+		if constraints := s.Constraints(); len(constraints) > 0 {
+			description = strings.TrimSpace(fmt.Sprintf("%s (%s)", description, strings.Join(constraints, "; ")))
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Type, required, s.Default, s.FlagText(), description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,156 @@
+package usage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JaredReisinger/drone-plugin-helper/optional"
+)
+
+type usageGlobal struct {
+	Debug bool `doc:"enable verbose output"`
+}
+
+type usageParams struct {
+	usageGlobal
+	Namespace string              `doc:"the namespace to install into" validate:"required"`
+	Output    string              `validate:"oneof=table|json"`
+	Retries   optional.Value[int] `validate:"min=0,max=5"`
+	Values    []string            `doc:"values files"`
+	Wait      bool                `cmd:",no"`
+	Token     string              `cmd:",secret"`
+	Chart     string              `cmd:",positional"`
+}
+
+func TestFor(t *testing.T) {
+	u, err := For(reflect.TypeOf(&usageParams{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	examples := []struct {
+		name     string
+		typ      string
+		required bool
+		flag     string
+	}{
+		{"debug", "boolean", false, "--debug"},
+		{"namespace", "string", true, "--namespace"},
+		{"output", "string", false, "--output"},
+		{"retries", "integer", false, "--retries"},
+		{"values", "list of string", false, "--values"},
+		{"wait", "boolean", false, "--wait/--no-wait"},
+		{"token", "string", false, "--token"},
+		{"chart", "string", false, "(positional)"},
+	}
+
+	if len(u.Settings) != len(examples) {
+		t.Fatalf("expected %d settings, got %d", len(examples), len(u.Settings))
+	}
+	for i, ex := range examples {
+		s := u.Settings[i]
+		if s.Name != ex.name || s.Type != ex.typ || s.Required != ex.required || s.FlagText() != ex.flag {
+			t.Errorf("expected %+v, got %+v (flag %q)", ex, s, s.FlagText())
+		}
+	}
+
+	if expected := "enable verbose output"; u.Settings[0].Description != expected {
+		t.Errorf("expected %q, got %q", expected, u.Settings[0].Description)
+	}
+	if expected := []string{"at least 0", "at most 5"}; !reflect.DeepEqual(u.Settings[3].Constraints(), expected) {
+		t.Errorf("expected %q, got %q", expected, u.Settings[3].Constraints())
+	}
+}
+
+func TestOfDefaults(t *testing.T) {
+	u, err := Of(&usageParams{
+		Namespace: "default",
+		Retries:   optional.Of(0),
+		Values:    []string{"a.yaml", "b.yaml"},
+		Token:     "hunter2",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"", "default", "", "0", "a.yaml,b.yaml", "", "******", ""}
+	for i, s := range u.Settings {
+		if s.Default != expected[i] {
+			t.Errorf("%s: expected %q, got %q", s.Name, expected[i], s.Default)
+		}
+	}
+}
+
+func TestOfUnexported(t *testing.T) {
+	u, err := Of(&struct {
+		Name       string
+		maxRetries int
+	}{"demo", 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, s := range u.Settings {
+		if s.Path == "maxRetries" && s.Default != "" {
+			t.Errorf("expected no default for unexported field, got %q", s.Default)
+		}
+	}
+	if expected := "demo"; u.Settings[0].Default != expected {
+		t.Errorf("expected %q, got %q", expected, u.Settings[0].Default)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	u, err := For(reflect.TypeOf(struct {
+		Namespace string `doc:"the namespace" validate:"required"`
+		Output    string `validate:"oneof=table|json"`
+	}{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var b strings.Builder
+	err = u.Write(&b, "Settings for helm install:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"Settings for helm install:",
+		"",
+		"  SETTING    TYPE    REQUIRED  DEFAULT  FLAG         DESCRIPTION",
+		"  namespace  string  yes                --namespace  the namespace",
+		"  output     string                     --output     (one of table, json)",
+		"",
+	}, "\n")
+	if actual := b.String(); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestForErrors(t *testing.T) {
+	examples := []struct {
+		name string
+		typ  reflect.Type
+	}{
+		{"not a struct", reflect.TypeOf(42)},
+		{"flag conflict", reflect.TypeOf(struct {
+			A string `cmd:"--same"`
+			B string `cmd:"--same"`
+		}{})},
+		{"validate tag", reflect.TypeOf(struct {
+			A string `validate:"bogus"`
+		}{})},
+	}
+
+	for _, ex := range examples {
+		local := ex
+		t.Run(local.name, func(t *testing.T) {
+			_, err := For(local.typ)
+			if err == nil {
+				t.Error("missing expected error")
+			}
+		})
+	}
+}